@@ -14,26 +14,35 @@ var defaultConfig = Config{
 }
 
 type Config struct {
-	initial    bool
-	BaseDelay  time.Duration
-	MaxDelay   time.Duration
-	Multiplier float64
-	Jitter     float64
+	initial        bool
+	BaseDelay      time.Duration
+	MaxDelay       time.Duration
+	Multiplier     float64
+	Jitter         float64
+	MaxAttempts    int           // 最多执行的次数(包含第一次), <=0表示不限制
+	MaxElapsedTime time.Duration // 从开始到结束允许的最长耗时, <=0表示不限制
 }
 
 func NewConfig() *Config {
 	return &Config{initial: true}
 }
 
-// Get 获取延时
-func Get(config *Config, retries int) time.Duration {
+func checkConfig(config *Config) *Config {
 	if config == nil {
-		config = &defaultConfig
-	} else {
-		if !config.initial {
-			panic("Config must be initialized by NewConfig()")
-		}
+		return &defaultConfig
 	}
+	if !config.initial {
+		panic("Config must be initialized by NewConfig()")
+	}
+	return config
+}
+
+// Get 获取延时
+func Get(config *Config, retries int) time.Duration {
+	return exponentialDelay(checkConfig(config), retries, rands.Float64)
+}
+
+func exponentialDelay(config *Config, retries int, random func() float64) time.Duration {
 	if retries <= 0 {
 		return config.BaseDelay
 	}
@@ -46,9 +55,83 @@ func Get(config *Config, retries int) time.Duration {
 		backoff = max
 	}
 
-	backoff *= 1 + config.Jitter*(rands.Float64()*2-1)
+	backoff *= 1 + config.Jitter*(random()*2-1)
 	if backoff < 0 {
 		return 0
 	}
 	return time.Duration(backoff)
 }
+
+// Stop Backoff.Next返回Stop时表示不应该再重试
+const Stop time.Duration = -1
+
+// Backoff 带有状态的退避器, 记录已经重试的次数和开始时间, 非线程安全
+type Backoff struct {
+	config   *Config
+	strategy Strategy
+	clock    Clock
+	attempt  int
+	start    time.Time
+}
+
+// NewBackoff 根据config创建Backoff, config为nil时使用默认配置
+func NewBackoff(config *Config, opts ...Option) *Backoff {
+	return newBackoff(config, newOptions(opts...))
+}
+
+func newBackoff(config *Config, o *options) *Backoff {
+	config = checkConfig(config)
+	if o.clock == nil {
+		o.clock = realClock{}
+	}
+
+	var strategy Strategy
+	if o.newStrategy != nil {
+		strategy = o.newStrategy()
+	} else {
+		strategy = NewExponential(config)
+	}
+	if r, ok := strategy.(randomizer); ok && o.random != nil {
+		r.setRandom(o.random)
+	}
+
+	return &Backoff{
+		config:   config,
+		strategy: strategy,
+		clock:    o.clock,
+		start:    o.clock.Now(),
+	}
+}
+
+// Next 返回下一次重试前需要等待的时间, 超过最大次数或最长耗时时返回Stop
+func (b *Backoff) Next() time.Duration {
+	delay, err := b.next()
+	if err != nil {
+		return Stop
+	}
+	return delay
+}
+
+func (b *Backoff) next() (time.Duration, error) {
+	if b.config.MaxAttempts > 0 && b.attempt+1 >= b.config.MaxAttempts {
+		return Stop, ErrMaxAttempts
+	}
+	delay := b.strategy.NextDelay(b.attempt)
+	if b.config.MaxElapsedTime > 0 && b.clock.Now().Sub(b.start)+delay > b.config.MaxElapsedTime {
+		return Stop, ErrMaxElapsedTime
+	}
+	b.attempt++
+	return delay, nil
+}
+
+// Reset 重置重试次数和开始时间
+func (b *Backoff) Reset() {
+	b.attempt = 0
+	b.start = b.clock.Now()
+	b.strategy.Reset()
+}
+
+// Attempt 已经重试的次数
+func (b *Backoff) Attempt() int {
+	return b.attempt
+}
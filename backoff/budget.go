@@ -0,0 +1,110 @@
+package backoff
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrBudgetExhausted = errors.New("retry budget exhausted")
+
+// BudgetStats 重试预算的统计信息, Requests和Retries为统计窗口内的数量, Denied为累计被拒绝的重试次数
+type BudgetStats struct {
+	Requests int64
+	Retries  int64
+	Denied   int64
+}
+
+type budgetBucket struct {
+	second   int64
+	requests int64
+	retries  int64
+}
+
+// RetryBudget 进程内共享的重试预算, 限制统计窗口内重试次数占请求次数的比例,
+// 避免下游故障时所有调用方各自重试而放大流量
+type RetryBudget struct {
+	mu           sync.Mutex
+	ratio        float64
+	minPerSecond int
+	buckets      []budgetBucket
+	denied       int64
+	now          func() time.Time
+}
+
+// NewRetryBudget 创建重试预算: 窗口内的重试次数最多为请求次数的ratio倍, 但每秒至少允许minPerSecond次重试,
+// window为统计窗口, 按秒计算, <=0时默认为10秒
+func NewRetryBudget(ratio float64, minPerSecond int, window time.Duration) *RetryBudget {
+	if ratio < 0 {
+		ratio = 0
+	}
+	if minPerSecond < 0 {
+		minPerSecond = 0
+	}
+	seconds := int((window + time.Second - 1) / time.Second)
+	if seconds <= 0 {
+		seconds = 10
+	}
+	return &RetryBudget{
+		ratio:        ratio,
+		minPerSecond: minPerSecond,
+		buckets:      make([]budgetBucket, seconds),
+		now:          time.Now,
+	}
+}
+
+// current 返回当前秒对应的桶, 过期的桶会被清零
+func (rb *RetryBudget) current() *budgetBucket {
+	second := rb.now().Unix()
+	b := &rb.buckets[int(second%int64(len(rb.buckets)))]
+	if b.second != second {
+		*b = budgetBucket{second: second}
+	}
+	return b
+}
+
+func (rb *RetryBudget) sum() (requests, retries int64) {
+	oldest := rb.now().Unix() - int64(len(rb.buckets))
+	for _, b := range rb.buckets {
+		if b.second > oldest {
+			requests += b.requests
+			retries += b.retries
+		}
+	}
+	return
+}
+
+// Deposit 记录一次请求(首次执行), 请求越多可用于重试的预算越多
+func (rb *RetryBudget) Deposit() {
+	rb.mu.Lock()
+	rb.current().requests++
+	rb.mu.Unlock()
+}
+
+// Withdraw 申请一次重试, 预算不足时返回false并记为被拒绝
+func (rb *RetryBudget) Withdraw() bool {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	requests, retries := rb.sum()
+	limit := float64(rb.minPerSecond*len(rb.buckets)) + rb.ratio*float64(requests)
+	if float64(retries+1) > limit {
+		rb.denied++
+		return false
+	}
+	rb.current().retries++
+	return true
+}
+
+// Stats 返回当前的统计信息
+func (rb *RetryBudget) Stats() BudgetStats {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	requests, retries := rb.sum()
+	return BudgetStats{
+		Requests: requests,
+		Retries:  retries,
+		Denied:   rb.denied,
+	}
+}
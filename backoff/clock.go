@@ -0,0 +1,70 @@
+package backoff
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pyihe/go-pkg/times"
+)
+
+// Clock 时钟, 用于计算耗时和等待
+type Clock interface {
+	Now() time.Time
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Sleep(ctx context.Context, d time.Duration) error {
+	return times.SleepWithContext(ctx, d)
+}
+
+// Recorder 用于测试的Clock, Sleep不会阻塞, 只推进当前时间并记录每次等待的时长
+type Recorder struct {
+	mu     sync.Mutex
+	now    time.Time
+	delays []time.Duration
+}
+
+// NewRecorder 创建从start开始计时的Recorder
+func NewRecorder(start time.Time) *Recorder {
+	return &Recorder{now: start}
+}
+
+func (r *Recorder) Now() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.now
+}
+
+func (r *Recorder) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.now = r.now.Add(d)
+	r.delays = append(r.delays, d)
+	r.mu.Unlock()
+	return nil
+}
+
+// Advance 将当前时间向后推进d, 不记录为等待
+func (r *Recorder) Advance(d time.Duration) {
+	r.mu.Lock()
+	r.now = r.now.Add(d)
+	r.mu.Unlock()
+}
+
+// Delays 按顺序返回所有等待过的时长
+func (r *Recorder) Delays() []time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delays := make([]time.Duration, len(r.delays))
+	copy(delays, r.delays)
+	return delays
+}
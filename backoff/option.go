@@ -0,0 +1,52 @@
+package backoff
+
+import (
+	"math/rand"
+)
+
+// Option Retry和Backoff的配置项
+type Option func(*options)
+
+type options struct {
+	newStrategy func() Strategy
+	random      func() float64
+	clock       Clock
+	budget      *RetryBudget
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{}
+	for _, op := range opts {
+		op(o)
+	}
+	return o
+}
+
+// WithStrategy 指定退避策略, 策略可能带有状态, 所以每个Backoff(包括Retry内部创建的)都会调用newStrategy创建新的实例
+func WithStrategy(newStrategy func() Strategy) Option {
+	return func(o *options) {
+		o.newStrategy = newStrategy
+	}
+}
+
+// WithRandSource 指定带有抖动的策略所使用的随机源, 固定种子的随机源可以使延时序列可复现
+func WithRandSource(src rand.Source) Option {
+	random := lockedFloat64(src)
+	return func(o *options) {
+		o.random = random
+	}
+}
+
+// WithClock 指定计时和等待所使用的时钟, 测试时可以使用Recorder
+func WithClock(c Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithBudget 指定Retry使用的重试预算, 多个Retry共享同一个预算时可以限制整个进程的重试量
+func WithBudget(b *RetryBudget) Option {
+	return func(o *options) {
+		o.budget = b
+	}
+}
@@ -0,0 +1,124 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrMaxAttempts    = errors.New("max attempts reached")
+	ErrMaxElapsedTime = errors.New("max elapsed time reached")
+)
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent 将err标记为不可重试的错误, Retry遇到此类错误时立即返回
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent 判断err是否为不可重试的错误
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// RetryError Retry失败时返回的错误, 按顺序记录了每次执行返回的错误
+type RetryError struct {
+	Errors []error // 每次执行返回的错误
+	Reason error   // 停止重试的原因, 为nil时表示遇到了不可重试的错误
+}
+
+func (e *RetryError) Error() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("retry failed after %d attempt(s)", len(e.Errors)))
+	if e.Reason != nil {
+		b.WriteString(" (")
+		b.WriteString(e.Reason.Error())
+		b.WriteString(")")
+	}
+	for i, err := range e.Errors {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
+		}
+		b.WriteString(fmt.Sprintf("attempt %d: %v", i+1, err))
+	}
+	return b.String()
+}
+
+// Unwrap 返回最后一次执行的错误
+func (e *RetryError) Unwrap() error {
+	if len(e.Errors) == 0 {
+		return e.Reason
+	}
+	return e.Errors[len(e.Errors)-1]
+}
+
+// Is 使errors.Is可以匹配停止重试的原因, 如context.Canceled、ErrMaxAttempts
+func (e *RetryError) Is(target error) bool {
+	return e.Reason != nil && errors.Is(e.Reason, target)
+}
+
+// Retry 执行fn, 失败时按照退避策略(默认为config对应的指数退避)计算的延时进行重试, 直到成功、遇到不可重试的错误、
+// 达到最大次数或最长耗时、重试预算不足、ctx被取消, config为nil时使用默认配置
+func Retry(ctx context.Context, config *Config, fn func() error, opts ...Option) error {
+	_, err := RetryWithData(ctx, config, func() (struct{}, error) {
+		return struct{}{}, fn()
+	}, opts...)
+	return err
+}
+
+// RetryWithData 同Retry, fn成功时返回其结果
+func RetryWithData[T any](ctx context.Context, config *Config, fn func() (T, error), opts ...Option) (data T, err error) {
+	var (
+		zero T
+		errs []error
+		o    = newOptions(opts...)
+		b    = newBackoff(config, o)
+	)
+	if o.budget != nil {
+		o.budget.Deposit()
+	}
+	for {
+		if err = ctx.Err(); err != nil {
+			return zero, &RetryError{Errors: errs, Reason: err}
+		}
+
+		data, err = fn()
+		if err == nil {
+			return data, nil
+		}
+		errs = append(errs, err)
+		if IsPermanent(err) {
+			return zero, &RetryError{Errors: errs}
+		}
+
+		delay, reason := b.next()
+		if reason != nil {
+			return zero, &RetryError{Errors: errs, Reason: reason}
+		}
+		if o.budget != nil && !o.budget.Withdraw() {
+			return zero, &RetryError{Errors: errs, Reason: ErrBudgetExhausted}
+		}
+		if err = b.clock.Sleep(ctx, delay); err != nil {
+			return zero, &RetryError{Errors: errs, Reason: err}
+		}
+	}
+}
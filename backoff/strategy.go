@@ -0,0 +1,166 @@
+package backoff
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pyihe/go-pkg/rands"
+)
+
+// Strategy 退避策略, attempt为已经重试的次数(从0开始)
+type Strategy interface {
+	NextDelay(attempt int) time.Duration
+	Reset()
+}
+
+// NewConstant 固定延时
+func NewConstant(delay time.Duration) Strategy {
+	return &constant{delay: delay}
+}
+
+// NewLinear 线性增长的延时: base + attempt*step, 最大不超过max(max<=0时不限制)
+func NewLinear(base, step, max time.Duration) Strategy {
+	return &linear{base: base, step: step, max: max}
+}
+
+// NewExponential 指数增长并带有乘性抖动的延时, 与Get的计算方式一致, config为nil时使用默认配置
+func NewExponential(config *Config) Strategy {
+	return &exponential{config: checkConfig(config), random: rands.SafeFloat64}
+}
+
+// NewFullJitter AWS的"Full Jitter"策略: 在[0, min(max, base*2^attempt))之间随机
+func NewFullJitter(base, max time.Duration) Strategy {
+	return &fullJitter{base: base, max: max, random: rands.SafeFloat64}
+}
+
+// NewDecorrelatedJitter AWS的"Decorrelated Jitter"策略: 在[base, prev*3)之间随机, 最大不超过max
+func NewDecorrelatedJitter(base, max time.Duration) Strategy {
+	return &decorrelatedJitter{base: base, max: max, prev: base, random: rands.SafeFloat64}
+}
+
+type randomizer interface {
+	setRandom(random func() float64)
+}
+
+func lockedFloat64(src rand.Source) func() float64 {
+	var (
+		mu sync.Mutex
+		r  = rand.New(src)
+	)
+	return func() float64 {
+		mu.Lock()
+		defer mu.Unlock()
+		return r.Float64()
+	}
+}
+
+type constant struct {
+	delay time.Duration
+}
+
+func (c *constant) NextDelay(int) time.Duration {
+	return c.delay
+}
+
+func (c *constant) Reset() {}
+
+type linear struct {
+	base time.Duration
+	step time.Duration
+	max  time.Duration
+}
+
+func (l *linear) NextDelay(attempt int) time.Duration {
+	if attempt < 0 {
+		attempt = 0
+	}
+	delay := float64(l.base) + float64(attempt)*float64(l.step)
+	if l.max > 0 && delay > float64(l.max) {
+		return l.max
+	}
+	if delay < 0 {
+		return 0
+	}
+	return time.Duration(delay)
+}
+
+func (l *linear) Reset() {}
+
+type exponential struct {
+	config *Config
+	random func() float64
+}
+
+func (e *exponential) NextDelay(attempt int) time.Duration {
+	return exponentialDelay(e.config, attempt, e.random)
+}
+
+func (e *exponential) Reset() {}
+
+func (e *exponential) setRandom(random func() float64) {
+	e.random = random
+}
+
+type fullJitter struct {
+	base   time.Duration
+	max    time.Duration
+	random func() float64
+}
+
+func (f *fullJitter) NextDelay(attempt int) time.Duration {
+	if attempt < 0 {
+		attempt = 0
+	}
+	ceil := float64(f.base) * math.Pow(2, float64(attempt))
+	if f.max > 0 && ceil > float64(f.max) {
+		ceil = float64(f.max)
+	}
+	return time.Duration(ceil * f.random())
+}
+
+func (f *fullJitter) Reset() {}
+
+func (f *fullJitter) setRandom(random func() float64) {
+	f.random = random
+}
+
+type decorrelatedJitter struct {
+	mu     sync.Mutex
+	base   time.Duration
+	max    time.Duration
+	prev   time.Duration
+	random func() float64
+}
+
+func (d *decorrelatedJitter) NextDelay(attempt int) time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if attempt <= 0 {
+		d.prev = d.base
+	}
+	lower, upper := float64(d.base), float64(d.prev)*3
+	if upper < lower {
+		upper = lower
+	}
+	delay := lower + (upper-lower)*d.random()
+	if d.max > 0 && delay > float64(d.max) {
+		delay = float64(d.max)
+	}
+	d.prev = time.Duration(delay)
+	return d.prev
+}
+
+func (d *decorrelatedJitter) Reset() {
+	d.mu.Lock()
+	d.prev = d.base
+	d.mu.Unlock()
+}
+
+func (d *decorrelatedJitter) setRandom(random func() float64) {
+	d.mu.Lock()
+	d.random = random
+	d.mu.Unlock()
+}
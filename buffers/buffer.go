@@ -5,17 +5,6 @@ import (
 	"sync"
 )
 
-//type ByteBuffer = bytebufferpool.ByteBuffer
-//
-//var (
-//	Get = bytebufferpool.Get
-//	Put = func(b *ByteBuffer) {
-//		if b != nil {
-//			bytebufferpool.Put(b)
-//		}
-//	}
-//)
-
 var bp sync.Pool
 
 func Get() *bytes.Buffer {
@@ -0,0 +1,232 @@
+package buffers
+
+import (
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pyihe/go-pkg/bytespool"
+)
+
+// 参考 github.com/valyala/bytebufferpool
+
+const (
+	minBitSize = 6 // 最小容量等级为2^6=64字节
+	steps      = 20
+
+	minSize = 1 << minBitSize
+
+	calibrateCallsThreshold = 42000
+	maxPercentile           = 0.95
+)
+
+// ByteBuffer 可复用的字节缓冲区, 底层数组从bytespool中获取
+type ByteBuffer struct {
+	// B 已写入的内容, 可以直接读写; 与bytes.Buffer一样, 扩容时旧的底层数组会被归还到bytespool,
+	// 因此在B上保留的切片只在下一次写入、Reset或PutByteBuffer之前有效
+	B []byte
+}
+
+// Len 返回已写入的字节数
+func (b *ByteBuffer) Len() int {
+	return len(b.B)
+}
+
+// Bytes 返回已写入的内容, 只在下一次写入、Reset或PutByteBuffer之前有效, 需要保留时应当复制
+func (b *ByteBuffer) Bytes() []byte {
+	return b.B
+}
+
+// String 以字符串形式返回已写入的内容
+func (b *ByteBuffer) String() string {
+	return string(b.B)
+}
+
+// Reset 清空内容, 保留底层数组
+func (b *ByteBuffer) Reset() {
+	b.B = b.B[:0]
+}
+
+// grow 保证还可以写入n个字节, 底层数组不够时从bytespool中重新获取
+func (b *ByteBuffer) grow(n int) {
+	size := len(b.B) + n
+	if size <= cap(b.B) {
+		return
+	}
+	c := 2 * cap(b.B)
+	if c < size {
+		c = size
+	}
+	buf := bytespool.Get(len(b.B), c)
+	copy(buf, b.B)
+	bytespool.Put(b.B)
+	b.B = buf
+}
+
+// Write 实现io.Writer
+func (b *ByteBuffer) Write(p []byte) (int, error) {
+	b.grow(len(p))
+	b.B = append(b.B, p...)
+	return len(p), nil
+}
+
+// WriteByte 实现io.ByteWriter
+func (b *ByteBuffer) WriteByte(c byte) error {
+	b.grow(1)
+	b.B = append(b.B, c)
+	return nil
+}
+
+// WriteString 实现io.StringWriter
+func (b *ByteBuffer) WriteString(s string) (int, error) {
+	b.grow(len(s))
+	b.B = append(b.B, s...)
+	return len(s), nil
+}
+
+// Set 将内容设置为p
+func (b *ByteBuffer) Set(p []byte) {
+	b.Reset()
+	_, _ = b.Write(p)
+}
+
+// SetString 将内容设置为s
+func (b *ByteBuffer) SetString(s string) {
+	b.Reset()
+	_, _ = b.WriteString(s)
+}
+
+// ReadFrom 实现io.ReaderFrom, 从r中读取数据直到io.EOF
+func (b *ByteBuffer) ReadFrom(r io.Reader) (int64, error) {
+	var total int64
+	for {
+		if len(b.B) == cap(b.B) {
+			b.grow(minSize)
+		}
+		n, err := r.Read(b.B[len(b.B):cap(b.B)])
+		b.B = b.B[:len(b.B)+n]
+		total += int64(n)
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// WriteTo 实现io.WriterTo
+func (b *ByteBuffer) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(b.B)
+	return int64(n), err
+}
+
+// ByteBufferPool ByteBuffer的缓存池, 会根据Put时的大小定期校准:
+// 新建的ByteBuffer使用最常见的容量, 超过95%分位容量的ByteBuffer会被直接丢弃
+type ByteBufferPool struct {
+	calls       [steps]uint64
+	calibrating uint64
+
+	defaultSize uint64
+	maxSize     uint64
+
+	pool sync.Pool
+}
+
+var defaultByteBufferPool ByteBufferPool
+
+// GetByteBuffer 从默认缓存池获取ByteBuffer
+func GetByteBuffer() *ByteBuffer {
+	return defaultByteBufferPool.Get()
+}
+
+// PutByteBuffer 将ByteBuffer归还到默认缓存池
+func PutByteBuffer(b *ByteBuffer) {
+	if b == nil {
+		return
+	}
+	defaultByteBufferPool.Put(b)
+}
+
+// Get 获取一个空的ByteBuffer
+func (p *ByteBufferPool) Get() *ByteBuffer {
+	if b, ok := p.pool.Get().(*ByteBuffer); ok {
+		return b
+	}
+	return &ByteBuffer{
+		B: bytespool.Get(0, int(atomic.LoadUint64(&p.defaultSize))),
+	}
+}
+
+// Put 归还ByteBuffer, 归还后不能再使用b
+func (p *ByteBufferPool) Put(b *ByteBuffer) {
+	idx := index(len(b.B))
+	if atomic.AddUint64(&p.calls[idx], 1) > calibrateCallsThreshold {
+		p.calibrate()
+	}
+
+	maxSize := int(atomic.LoadUint64(&p.maxSize))
+	if maxSize == 0 || cap(b.B) <= maxSize {
+		b.Reset()
+		p.pool.Put(b)
+	}
+}
+
+type callSize struct {
+	calls uint64
+	size  uint64
+}
+
+func (p *ByteBufferPool) calibrate() {
+	if !atomic.CompareAndSwapUint64(&p.calibrating, 0, 1) {
+		return
+	}
+
+	a := make([]callSize, 0, steps)
+	var callsSum uint64
+	for i := uint64(0); i < steps; i++ {
+		calls := atomic.SwapUint64(&p.calls[i], 0)
+		callsSum += calls
+		a = append(a, callSize{
+			calls: calls,
+			size:  minSize << i,
+		})
+	}
+	sort.Slice(a, func(i, j int) bool {
+		return a[i].calls > a[j].calls
+	})
+
+	defaultSize := a[0].size
+	maxSize := defaultSize
+
+	maxSum := uint64(float64(callsSum) * maxPercentile)
+	callsSum = 0
+	for i := 0; i < steps; i++ {
+		if callsSum > maxSum {
+			break
+		}
+		callsSum += a[i].calls
+		if size := a[i].size; size > maxSize {
+			maxSize = size
+		}
+	}
+
+	atomic.StoreUint64(&p.defaultSize, defaultSize)
+	atomic.StoreUint64(&p.maxSize, maxSize)
+	atomic.StoreUint64(&p.calibrating, 0)
+}
+
+func index(n int) int {
+	n--
+	n >>= minBitSize
+	idx := 0
+	for n > 0 {
+		n >>= 1
+		idx++
+	}
+	if idx >= steps {
+		idx = steps - 1
+	}
+	return idx
+}
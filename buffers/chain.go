@@ -0,0 +1,163 @@
+package buffers
+
+import (
+	"io"
+	"net"
+
+	"github.com/pyihe/go-pkg/bytespool"
+)
+
+const defaultChunkSize = 4096
+
+// ChainBuffer 由多个块组成的链式缓冲区, 写入时按块追加, 读取时可以不经拷贝地获得跨越多个块的数据,
+// 块的内存从bytespool中获取, 非线程安全
+type ChainBuffer struct {
+	chunks    [][]byte // 每个块的长度为已写入的位置
+	off       int      // 第一个块中已读取的位置
+	size      int      // 可读的字节数
+	chunkSize int
+}
+
+// NewChainBuffer 创建块大小为chunkSize的链式缓冲区, chunkSize<=0时默认为4096
+func NewChainBuffer(chunkSize int) *ChainBuffer {
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+	return &ChainBuffer{
+		chunkSize: chunkSize,
+	}
+}
+
+// Len 返回可读的字节数
+func (cb *ChainBuffer) Len() int {
+	return cb.size
+}
+
+// tail 返回最后一个还有剩余空间的块, 没有时新分配一个
+func (cb *ChainBuffer) tail() []byte {
+	if n := len(cb.chunks); n > 0 {
+		if last := cb.chunks[n-1]; len(last) < cap(last) {
+			return last
+		}
+	}
+	chunk := bytespool.Get(0, cb.chunkSize)
+	cb.chunks = append(cb.chunks, chunk)
+	return chunk
+}
+
+func (cb *ChainBuffer) setTail(chunk []byte) {
+	cb.chunks[len(cb.chunks)-1] = chunk
+}
+
+// Write 实现io.Writer
+func (cb *ChainBuffer) Write(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		chunk := cb.tail()
+		c := copy(chunk[len(chunk):cap(chunk)], p[n:])
+		cb.setTail(chunk[:len(chunk)+c])
+		n += c
+	}
+	cb.size += n
+	return n, nil
+}
+
+// Fill 调用一次r.Read将数据直接读入最后一个块的剩余空间, 适用于从网络连接中读取数据
+func (cb *ChainBuffer) Fill(r io.Reader) (int, error) {
+	chunk := cb.tail()
+	n, err := r.Read(chunk[len(chunk):cap(chunk)])
+	if n > 0 {
+		cb.setTail(chunk[:len(chunk)+n])
+		cb.size += n
+	}
+	return n, err
+}
+
+// Peek 返回最多n个可读的字节但不移动读位置, 数据跨越多个块时按块分段返回(类似readv/writev的iovec),
+// 返回的切片引用内部内存, 在下一次Discard或Read前有效
+func (cb *ChainBuffer) Peek(n int) [][]byte {
+	if n > cb.size {
+		n = cb.size
+	}
+	var result [][]byte
+	off := cb.off
+	for _, chunk := range cb.chunks {
+		if n <= 0 {
+			break
+		}
+		data := chunk[off:]
+		off = 0
+		if len(data) > n {
+			data = data[:n]
+		}
+		if len(data) > 0 {
+			result = append(result, data)
+		}
+		n -= len(data)
+	}
+	return result
+}
+
+// Discard 丢弃n个可读的字节, 读完的块会归还到bytespool
+func (cb *ChainBuffer) Discard(n int) (int, error) {
+	if n < 0 {
+		return 0, ErrInvalidLength
+	}
+	if n > cb.size {
+		n = cb.size
+	}
+	discarded := n
+	for n > 0 {
+		chunk := cb.chunks[0]
+		remain := len(chunk) - cb.off
+		if n < remain {
+			cb.off += n
+			break
+		}
+		n -= remain
+		cb.off = 0
+		if len(cb.chunks) == 1 && len(chunk) < cap(chunk) {
+			// 最后一个块还可以继续写入, 重置后复用
+			cb.chunks[0] = chunk[:0]
+			break
+		}
+		bytespool.Put(chunk)
+		cb.chunks[0] = nil
+		cb.chunks = cb.chunks[1:]
+	}
+	cb.size -= discarded
+	return discarded, nil
+}
+
+// Read 实现io.Reader, 缓冲区为空时返回io.EOF
+func (cb *ChainBuffer) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if cb.size == 0 {
+		return 0, io.EOF
+	}
+	n := 0
+	for _, data := range cb.Peek(len(p)) {
+		n += copy(p[n:], data)
+	}
+	return cb.Discard(n)
+}
+
+// WriteTo 实现io.WriterTo, 将所有可读的数据写入w, w为网络连接时会使用writev
+func (cb *ChainBuffer) WriteTo(w io.Writer) (int64, error) {
+	buffers := net.Buffers(cb.Peek(cb.size))
+	n, err := buffers.WriteTo(w)
+	_, _ = cb.Discard(int(n))
+	return n, err
+}
+
+// Reset 清空缓冲区并将所有块归还到bytespool
+func (cb *ChainBuffer) Reset() {
+	for i, chunk := range cb.chunks {
+		bytespool.Put(chunk)
+		cb.chunks[i] = nil
+	}
+	cb.chunks = cb.chunks[:0]
+	cb.off, cb.size = 0, 0
+}
@@ -0,0 +1,146 @@
+package buffers
+
+import (
+	"io"
+
+	"github.com/pyihe/go-pkg/errors"
+)
+
+var (
+	ErrRingFull      = errors.New("ring buffer is full")
+	ErrInvalidLength = errors.New("invalid length")
+)
+
+// RingBuffer 固定容量的环形缓冲区, 读写过程中不会重新分配内存, 非线程安全
+type RingBuffer struct {
+	buf  []byte
+	r    int // 下一个读取的位置
+	w    int // 下一个写入的位置
+	full bool
+}
+
+// NewRingBuffer 创建容量为size的环形缓冲区
+func NewRingBuffer(size int) *RingBuffer {
+	if size <= 0 {
+		panic("ring buffer size must be positive")
+	}
+	return &RingBuffer{
+		buf: make([]byte, size),
+	}
+}
+
+// Cap 返回容量
+func (rb *RingBuffer) Cap() int {
+	return len(rb.buf)
+}
+
+// Len 返回可读的字节数
+func (rb *RingBuffer) Len() int {
+	switch {
+	case rb.full:
+		return len(rb.buf)
+	case rb.w >= rb.r:
+		return rb.w - rb.r
+	default:
+		return len(rb.buf) - rb.r + rb.w
+	}
+}
+
+// Free 返回可写的字节数
+func (rb *RingBuffer) Free() int {
+	return len(rb.buf) - rb.Len()
+}
+
+// Reset 清空缓冲区
+func (rb *RingBuffer) Reset() {
+	rb.r, rb.w, rb.full = 0, 0, false
+}
+
+// Peek 返回最多n个可读的字节但不移动读位置, 数据跨越缓冲区末尾时会分成两段返回,
+// 返回的切片引用内部内存, 在下一次写入前有效
+func (rb *RingBuffer) Peek(n int) (head, tail []byte) {
+	if size := rb.Len(); n > size {
+		n = size
+	}
+	if n <= 0 {
+		return nil, nil
+	}
+	if rb.r+n <= len(rb.buf) {
+		return rb.buf[rb.r : rb.r+n], nil
+	}
+	return rb.buf[rb.r:], rb.buf[:rb.r+n-len(rb.buf)]
+}
+
+// Discard 丢弃n个可读的字节
+func (rb *RingBuffer) Discard(n int) (int, error) {
+	if n < 0 {
+		return 0, ErrInvalidLength
+	}
+	if size := rb.Len(); n > size {
+		n = size
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	rb.r = (rb.r + n) % len(rb.buf)
+	rb.full = false
+	if rb.r == rb.w {
+		rb.Reset()
+	}
+	return n, nil
+}
+
+// Read 实现io.Reader, 缓冲区为空时返回io.EOF
+func (rb *RingBuffer) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if rb.Len() == 0 {
+		return 0, io.EOF
+	}
+	head, tail := rb.Peek(len(p))
+	n := copy(p, head)
+	n += copy(p[n:], tail)
+	return rb.Discard(n)
+}
+
+// Write 实现io.Writer, 剩余空间不足时写入能写下的部分并返回ErrRingFull
+func (rb *RingBuffer) Write(p []byte) (int, error) {
+	n := 0
+	for n < len(p) && !rb.full {
+		end := len(rb.buf)
+		if rb.w < rb.r {
+			end = rb.r
+		}
+		c := copy(rb.buf[rb.w:end], p[n:])
+		n += c
+		rb.advance(c)
+	}
+	if n < len(p) {
+		return n, ErrRingFull
+	}
+	return n, nil
+}
+
+// Fill 调用一次r.Read将数据直接读入剩余空间, 适用于从网络连接中读取数据
+func (rb *RingBuffer) Fill(r io.Reader) (int, error) {
+	if rb.full {
+		return 0, ErrRingFull
+	}
+	end := len(rb.buf)
+	if rb.w < rb.r {
+		end = rb.r
+	}
+	n, err := r.Read(rb.buf[rb.w:end])
+	if n > 0 {
+		rb.advance(n)
+	}
+	return n, err
+}
+
+func (rb *RingBuffer) advance(n int) {
+	rb.w = (rb.w + n) % len(rb.buf)
+	if rb.w == rb.r && n > 0 {
+		rb.full = true
+	}
+}
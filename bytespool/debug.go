@@ -0,0 +1,31 @@
+package bytespool
+
+import "time"
+
+// Leak 已经Get但还未Put的buffer, 仅在使用bytespooldebug标签编译时记录
+type Leak struct {
+	Pool   string    // 所属Pool的名称
+	Size   int       // buffer的容量
+	Caller string    // 调用Get的位置
+	Since  time.Time // 调用Get的时间
+}
+
+// Leaks 返回默认Pool中所有已经Get但还未Put的buffer, 非debug模式下始终返回nil
+func Leaks() []Leak {
+	return defaultPool.Leaks()
+}
+
+// OnBadPut 设置默认Pool的错误Put回调
+func OnBadPut(handler func(size int, caller string)) {
+	defaultPool.OnBadPut(handler)
+}
+
+// Leaks 返回所有已经Get但还未Put的buffer, 非debug模式下始终返回nil
+func (p *Pool) Leaks() []Leak {
+	return p.tracker.leaks()
+}
+
+// OnBadPut 设置重复Put或者Put了不是由Get得到的buffer时的回调, 仅在debug模式下生效
+func (p *Pool) OnBadPut(handler func(size int, caller string)) {
+	p.tracker.setBadPutHandler(handler)
+}
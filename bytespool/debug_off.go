@@ -0,0 +1,19 @@
+//go:build !bytespooldebug
+
+package bytespool
+
+type tracker struct{}
+
+func (*tracker) init() {}
+
+func (*tracker) get(string, []byte) {}
+
+func (*tracker) put([]byte) bool {
+	return true
+}
+
+func (*tracker) leaks() []Leak {
+	return nil
+}
+
+func (*tracker) setBadPutHandler(func(size int, caller string)) {}
@@ -0,0 +1,80 @@
+//go:build bytespooldebug
+
+package bytespool
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+type tracker struct {
+	mu            sync.Mutex
+	outstanding   map[uintptr]Leak
+	badPutHandler func(size int, caller string)
+}
+
+func (t *tracker) init() {
+	t.outstanding = make(map[uintptr]Leak)
+	t.badPutHandler = func(size int, caller string) {
+		log.Printf("bytespool: bad Put of %d bytes at %s", size, caller)
+	}
+}
+
+func address(buf []byte) uintptr {
+	return uintptr(unsafe.Pointer(&buf[:cap(buf)][0]))
+}
+
+// caller 返回调用Get/Put的位置: 调用方 -> Get/Put -> get/put -> tracker
+func caller() string {
+	_, file, line, ok := runtime.Caller(4)
+	if !ok {
+		return "unknown"
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
+func (t *tracker) get(pool string, buf []byte) {
+	leak := Leak{
+		Pool:   pool,
+		Size:   cap(buf),
+		Caller: caller(),
+		Since:  time.Now(),
+	}
+	t.mu.Lock()
+	t.outstanding[address(buf)] = leak
+	t.mu.Unlock()
+}
+
+func (t *tracker) put(buf []byte) bool {
+	addr := address(buf)
+	t.mu.Lock()
+	_, ok := t.outstanding[addr]
+	delete(t.outstanding, addr)
+	handler := t.badPutHandler
+	t.mu.Unlock()
+
+	if !ok && handler != nil {
+		handler(cap(buf), caller())
+	}
+	return ok
+}
+
+func (t *tracker) leaks() []Leak {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	result := make([]Leak, 0, len(t.outstanding))
+	for _, leak := range t.outstanding {
+		result = append(result, leak)
+	}
+	return result
+}
+
+func (t *tracker) setBadPutHandler(handler func(size int, caller string)) {
+	t.mu.Lock()
+	t.badPutHandler = handler
+	t.mu.Unlock()
+}
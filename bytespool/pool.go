@@ -3,24 +3,82 @@ package bytespool
 import (
 	"math/bits"
 	"sync"
+	"sync/atomic"
 )
 
 // copy from github.com/bytedance/gopkg/tree/develop/lang/mcache
 
 const maxSize = 46
 
-var caches [maxSize]sync.Pool
+var defaultPool = NewPool(1, 1<<(maxSize-1), WithName("default"))
 
-func init() {
-	for i := 0; i < maxSize; i++ {
-		size := 1 << i
-		caches[i].New = func() any {
+// Option Pool的配置项
+type Option func(*Pool)
+
+// WithName 指定Pool的名称, 用于区分debug模式下不同Pool的记录
+func WithName(name string) Option {
+	return func(p *Pool) {
+		p.name = name
+	}
+}
+
+// Pool 按容量等级(2的幂)缓存[]byte, 不同的Pool之间互不影响
+type Pool struct {
+	name        string
+	minClass    int
+	maxPoolSize int64 // 允许缓存的最大容量, 超过的buffer不经过缓存
+	caches      [maxSize]sync.Pool
+	stats       [maxSize]classStats
+	tracker     tracker
+}
+
+// NewPool 创建只缓存容量在[minSize, maxPooled]之间的buffer的Pool, 容量会向上取整为2的幂,
+// 小于minSize的Get会得到容量为minSize的buffer, 大于maxPooled的Get直接分配内存
+func NewPool(minSize, maxPooled int, opts ...Option) *Pool {
+	p := &Pool{
+		minClass: locatePool(minSize),
+	}
+	p.SetMaxPoolSize(maxPooled)
+	for i := p.minClass; i < len(p.caches); i++ {
+		index, size := i, 1<<i
+		p.caches[i].New = func() any {
+			atomic.AddUint64(&p.stats[index].misses, 1)
 			return make([]byte, 0, size)
 		}
 	}
+	for _, op := range opts {
+		op(p)
+	}
+	p.tracker.init()
+	return p
 }
 
-func Get(length int, capacity ...int) []byte {
+// Name 返回Pool的名称
+func (p *Pool) Name() string {
+	return p.name
+}
+
+// SetMaxPoolSize 设置允许缓存的最大容量, 超过该容量的Get直接分配内存, Put直接丢弃, 避免大块内存常驻缓存
+// size会向下取整到2的幂, 与缓存的容量等级保持一致
+func (p *Pool) SetMaxPoolSize(size int) {
+	if size <= 0 || size > 1<<(maxSize-1) {
+		size = 1 << (maxSize - 1)
+	}
+	size = 1 << bsr(size)
+	atomic.StoreInt64(&p.maxPoolSize, int64(size))
+}
+
+// Get 获取长度为length的buffer, capacity用于指定最小容量
+func (p *Pool) Get(length int, capacity ...int) []byte {
+	return p.get(length, capacity)
+}
+
+// Put 归还buffer, 容量不是2的幂或者超出缓存范围的buffer会被直接丢弃
+func (p *Pool) Put(buf []byte) {
+	p.put(buf)
+}
+
+func (p *Pool) get(length int, capacity []int) []byte {
 	n := len(capacity)
 	if n > 1 {
 		panic("too many arguments")
@@ -29,18 +87,60 @@ func Get(length int, capacity ...int) []byte {
 	if n > 0 && capacity[0] > length {
 		c = capacity[0]
 	}
-	buf := caches[locatePool(c)].Get().([]byte)
+	index := locatePool(c)
+	if index < p.minClass {
+		index = p.minClass
+	}
+	// 按取整后的容量等级判断, 与put保持一致
+	if index >= maxSize || int64(1)<<index > atomic.LoadInt64(&p.maxPoolSize) {
+		return make([]byte, length, c)
+	}
+	buf := p.caches[index].Get().([]byte)
 	buf = buf[:length]
+	p.stats[index].get(cap(buf))
+	p.tracker.get(p.name, buf)
 	return buf
 }
 
-func Put(buf []byte) {
+func (p *Pool) put(buf []byte) {
 	size := cap(buf)
-	if !validSize(size) {
+	if size <= 0 || !validSize(size) {
+		return
+	}
+	index := bsr(size)
+	if index < p.minClass || index >= maxSize {
+		return
+	}
+	// 借出后调低了SetMaxPoolSize的buffer不再缓存, 但仍需要更新统计和追踪信息;
+	// 该等级没有借出记录时说明buffer是直接分配的, 与Pool无关
+	dropped := int64(size) > atomic.LoadInt64(&p.maxPoolSize)
+	if dropped && atomic.LoadInt64(&p.stats[index].outstanding) < int64(size) {
+		return
+	}
+	if !p.tracker.put(buf) {
+		return
+	}
+	p.stats[index].put(size)
+	if dropped {
 		return
 	}
 	buf = buf[:0]
-	caches[bsr(size)].Put(buf)
+	p.caches[index].Put(buf)
+}
+
+// SetMaxPoolSize 设置默认Pool允许缓存的最大容量
+func SetMaxPoolSize(size int) {
+	defaultPool.SetMaxPoolSize(size)
+}
+
+// Get 从默认Pool获取buffer
+func Get(length int, capacity ...int) []byte {
+	return defaultPool.get(length, capacity)
+}
+
+// Put 将buffer归还到默认Pool
+func Put(buf []byte) {
+	defaultPool.put(buf)
 }
 
 func locatePool(size int) int {
@@ -0,0 +1,56 @@
+package bytespool
+
+import "sync/atomic"
+
+// ClassStats 某个容量等级的统计信息
+type ClassStats struct {
+	Size        int    // 该等级buffer的容量
+	Gets        uint64 // Get的次数
+	Puts        uint64 // Put的次数
+	Misses      uint64 // 缓存中没有可用buffer而新分配的次数
+	Outstanding int64  // 已经Get但还未Put的字节数
+}
+
+type classStats struct {
+	gets        uint64
+	puts        uint64
+	misses      uint64
+	outstanding int64
+}
+
+func (s *classStats) get(size int) {
+	atomic.AddUint64(&s.gets, 1)
+	atomic.AddInt64(&s.outstanding, int64(size))
+}
+
+func (s *classStats) put(size int) {
+	atomic.AddUint64(&s.puts, 1)
+	atomic.AddInt64(&s.outstanding, -int64(size))
+}
+
+// Stats 返回默认Pool的统计信息
+func Stats() []ClassStats {
+	return defaultPool.Stats()
+}
+
+// Stats 返回所有被使用过的容量等级的统计信息, 按容量从小到大排列
+func (p *Pool) Stats() []ClassStats {
+	var (
+		result []ClassStats
+		stats  = &p.stats
+	)
+	for i := range stats {
+		s := ClassStats{
+			Size:        1 << i,
+			Gets:        atomic.LoadUint64(&stats[i].gets),
+			Puts:        atomic.LoadUint64(&stats[i].puts),
+			Misses:      atomic.LoadUint64(&stats[i].misses),
+			Outstanding: atomic.LoadInt64(&stats[i].outstanding),
+		}
+		if s.Gets == 0 && s.Puts == 0 && s.Misses == 0 {
+			continue
+		}
+		result = append(result, s)
+	}
+	return result
+}
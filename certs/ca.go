@@ -0,0 +1,203 @@
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"time"
+)
+
+// KeyType 私钥类型
+type KeyType int
+
+const (
+	KeyRSA       KeyType = iota // RSA 2048
+	KeyECDSAP256                // ECDSA P-256
+	KeyEd25519                  // Ed25519
+)
+
+// Usage 证书用途, 可以组合使用: UsageServer | UsageClient
+type Usage int
+
+const (
+	UsageServer Usage = 1 << iota // 服务端证书
+	UsageClient                   // 客户端证书
+)
+
+var (
+	ErrUnknownKeyType = errors.New("unknown key type")
+	ErrEmptyHosts     = errors.New("empty hosts")
+)
+
+// Certificate 证书及其PEM编码
+type Certificate struct {
+	TLS     tls.Certificate   // 可直接用于tls.Config
+	Leaf    *x509.Certificate // 解析后的证书
+	CertPEM []byte            // PEM编码的证书
+	KeyPEM  []byte            // PEM编码的私钥(PKCS#8)
+}
+
+// WriteFiles 将证书和私钥写入文件, 可用于https.NewTLSClient等需要文件路径的地方
+func (c *Certificate) WriteFiles(certFile, keyFile string) error {
+	if err := ioutil.WriteFile(certFile, c.CertPEM, 0644); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(keyFile, c.KeyPEM, 0600)
+}
+
+// CA 自签名的证书颁发机构
+type CA struct {
+	Certificate
+	key      crypto.Signer
+	keyType  KeyType
+	validity time.Duration
+}
+
+// NewCA 创建自签名的CA, validity为有效期, 签发的证书使用相同的有效期和私钥类型
+func NewCA(subject pkix.Name, validity time.Duration, keyType KeyType) (*CA, error) {
+	key, err := generateKey(keyType)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject,
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	cert, err := newCertificate(template, template, key, key)
+	if err != nil {
+		return nil, err
+	}
+	return &CA{
+		Certificate: *cert,
+		key:         key,
+		keyType:     keyType,
+		validity:    validity,
+	}, nil
+}
+
+// CertPool 返回只包含该CA的证书池, 可用于tls.Config的RootCAs和ClientCAs
+func (ca *CA) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+	return pool
+}
+
+// Issue 为hosts签发证书, hosts可以是域名或IP, 第一个host作为证书的CommonName
+func (ca *CA) Issue(hosts []string, usage Usage) (*Certificate, error) {
+	if len(hosts) == 0 {
+		return nil, ErrEmptyHosts
+	}
+	key, err := generateKey(ca.keyType)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	notAfter := now.Add(ca.validity)
+	if notAfter.After(ca.Leaf.NotAfter) {
+		notAfter = ca.Leaf.NotAfter
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   hosts[0],
+			Organization: ca.Leaf.Subject.Organization,
+		},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	if usage&UsageServer != 0 {
+		template.ExtKeyUsage = append(template.ExtKeyUsage, x509.ExtKeyUsageServerAuth)
+	}
+	if usage&UsageClient != 0 {
+		template.ExtKeyUsage = append(template.ExtKeyUsage, x509.ExtKeyUsageClientAuth)
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+
+	cert, err := newCertificate(template, ca.Leaf, key, ca.key)
+	if err != nil {
+		return nil, err
+	}
+	cert.TLS.Certificate = append(cert.TLS.Certificate, ca.Leaf.Raw)
+	return cert, nil
+}
+
+func generateKey(keyType KeyType) (crypto.Signer, error) {
+	switch keyType {
+	case KeyRSA:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case KeyECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, ErrUnknownKeyType
+	}
+}
+
+func newSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+func newCertificate(template, parent *x509.Certificate, key, parentKey crypto.Signer) (*Certificate, error) {
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Certificate{
+		TLS: tls.Certificate{
+			Certificate: [][]byte{der},
+			PrivateKey:  key,
+			Leaf:        leaf,
+		},
+		Leaf:    leaf,
+		CertPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		KeyPEM:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+	}, nil
+}
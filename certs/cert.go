@@ -2,31 +2,14 @@ package certs
 
 import (
 	"crypto/tls"
-	"encoding/pem"
-	"errors"
-	"io/ioutil"
-
-	"golang.org/x/crypto/pkcs12"
 )
 
+// P12ToPem 读取PKCS#12文件并转换为tls.Certificate, 证书链中包含文件中的所有CA证书
 func P12ToPem(p12Path string, password string) (cert *tls.Certificate, err error) {
-	if p12Path == "" {
-		err = errors.New("empty path")
-		return
-	}
-	data, err := ioutil.ReadFile(p12Path)
+	bundle, err := LoadP12(p12Path, password)
 	if err != nil {
 		return nil, err
 	}
-	blocks, err := pkcs12.ToPEM(data, password)
-	if err != nil {
-		return nil, err
-	}
-
-	var pemData []byte
-	for _, b := range blocks {
-		pemData = append(pemData, pem.EncodeToMemory(b)...)
-	}
-	pemCert, err := tls.X509KeyPair(pemData, pemData)
-	return &pemCert, err
+	c := bundle.TLSCertificate()
+	return &c, nil
 }
@@ -0,0 +1,91 @@
+package certs
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// ExpiryEvent 证书即将过期(或检查失败)的事件
+type ExpiryEvent struct {
+	File      string        // 证书文件路径
+	Info      *CertInfo     // 即将过期的证书, Err不为nil时为nil
+	Remaining time.Duration // 距离过期还剩余的时间, 已经过期时为负数
+	Err       error         // 读取或解析证书文件失败的原因
+}
+
+// ExpiryWatcher 按照cron表达式定期检查证书文件, 证书在指定时间内即将过期时触发回调,
+// 在证书被更新之前每次检查都会触发
+type ExpiryWatcher struct {
+	mu      sync.Mutex
+	files   map[string]struct{}
+	before  time.Duration
+	handler func(ExpiryEvent)
+	c       *cron.Cron
+}
+
+// NewExpiryWatcher 创建并启动ExpiryWatcher, spec为带秒的cron表达式, before为提前告警的时间,
+// 如提前30天告警: NewExpiryWatcher("0 0 9 * * *", 30*24*time.Hour, handler)
+func NewExpiryWatcher(spec string, before time.Duration, handler func(ExpiryEvent)) (*ExpiryWatcher, error) {
+	if handler == nil {
+		return nil, errors.New("handler cannot be nil")
+	}
+	w := &ExpiryWatcher{
+		files:   make(map[string]struct{}),
+		before:  before,
+		handler: handler,
+		c:       cron.New(cron.WithSeconds()),
+	}
+	if _, err := w.c.AddFunc(spec, w.Check); err != nil {
+		return nil, err
+	}
+	w.c.Start()
+	return w, nil
+}
+
+// AddFile 添加需要检查的证书文件
+func (w *ExpiryWatcher) AddFile(path string) {
+	w.mu.Lock()
+	w.files[path] = struct{}{}
+	w.mu.Unlock()
+}
+
+// DelFile 移除证书文件
+func (w *ExpiryWatcher) DelFile(path string) {
+	w.mu.Lock()
+	delete(w.files, path)
+	w.mu.Unlock()
+}
+
+// Check 立即检查所有证书文件
+func (w *ExpiryWatcher) Check() {
+	w.mu.Lock()
+	files := make([]string, 0, len(w.files))
+	for f := range w.files {
+		files = append(files, f)
+	}
+	w.mu.Unlock()
+	sort.Strings(files)
+
+	now := time.Now()
+	for _, f := range files {
+		inspection, err := InspectFile(f, nil)
+		if err != nil {
+			w.handler(ExpiryEvent{File: f, Err: err})
+			continue
+		}
+		for _, info := range inspection.Certificates {
+			if remaining := info.Remaining(now); remaining <= w.before {
+				w.handler(ExpiryEvent{File: f, Info: info, Remaining: remaining})
+			}
+		}
+	}
+}
+
+// Stop 停止定期检查
+func (w *ExpiryWatcher) Stop() {
+	<-w.c.Stop().Done()
+}
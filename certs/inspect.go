@@ -0,0 +1,149 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"time"
+)
+
+var ErrNoCertificateFound = errors.New("no certificate found")
+
+// CertInfo 证书的基本信息
+type CertInfo struct {
+	Subject            string
+	Issuer             string
+	DNSNames           []string
+	IPAddresses        []net.IP
+	EmailAddresses     []string
+	URIs               []string
+	KeyAlgorithm       string // 公钥算法, 如RSA-2048、ECDSA-P-256、Ed25519
+	SignatureAlgorithm string
+	SerialNumber       string // 十六进制的序列号
+	NotBefore          time.Time
+	NotAfter           time.Time
+	IsCA               bool
+	Certificate        *x509.Certificate
+}
+
+// Expired 判断证书在t时是否已经过期
+func (c *CertInfo) Expired(t time.Time) bool {
+	return t.After(c.NotAfter)
+}
+
+// Remaining 返回从t开始到证书过期还剩余的时间
+func (c *CertInfo) Remaining(t time.Time) time.Duration {
+	return c.NotAfter.Sub(t)
+}
+
+// Inspection 证书文件的检查结果
+type Inspection struct {
+	Certificates []*CertInfo // 按照文件中的顺序排列, 第一个视为叶子证书, 其余视为中间证书
+	VerifyError  error       // 叶子证书校验失败的原因, 为nil表示校验通过
+}
+
+// Valid 证书链是否校验通过
+func (i *Inspection) Valid() bool {
+	return i.VerifyError == nil
+}
+
+// InspectFile 读取并检查证书文件
+func InspectFile(path string, roots *x509.CertPool) (*Inspection, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Inspect(data, roots)
+}
+
+// Inspect 解析PEM(或DER)编码的证书并使用roots校验证书链, roots为nil时使用系统的根证书
+func Inspect(data []byte, roots *x509.CertPool) (*Inspection, error) {
+	certs, err := parseCertificates(data)
+	if err != nil {
+		return nil, err
+	}
+
+	inspection := &Inspection{}
+	intermediates := x509.NewCertPool()
+	for i, cert := range certs {
+		inspection.Certificates = append(inspection.Certificates, newCertInfo(cert))
+		if i > 0 {
+			intermediates.AddCert(cert)
+		}
+	}
+	_, inspection.VerifyError = certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return inspection, nil
+}
+
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 && len(data) > 0 {
+		// 不是PEM编码时尝试按DER解析
+		if parsed, err := x509.ParseCertificates(data); err == nil {
+			certs = parsed
+		}
+	}
+	if len(certs) == 0 {
+		return nil, ErrNoCertificateFound
+	}
+	return certs, nil
+}
+
+func newCertInfo(cert *x509.Certificate) *CertInfo {
+	info := &CertInfo{
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		DNSNames:           cert.DNSNames,
+		IPAddresses:        cert.IPAddresses,
+		EmailAddresses:     cert.EmailAddresses,
+		KeyAlgorithm:       keyAlgorithm(cert),
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		SerialNumber:       fmt.Sprintf("%X", cert.SerialNumber),
+		NotBefore:          cert.NotBefore,
+		NotAfter:           cert.NotAfter,
+		IsCA:               cert.IsCA,
+		Certificate:        cert,
+	}
+	for _, uri := range cert.URIs {
+		info.URIs = append(info.URIs, uri.String())
+	}
+	return info
+}
+
+func keyAlgorithm(cert *x509.Certificate) string {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA-%d", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA-" + key.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return cert.PublicKeyAlgorithm.String()
+	}
+}
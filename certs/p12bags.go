@@ -0,0 +1,472 @@
+package certs
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// 解析PKCS#12中的SafeBag(RFC 7292), 只支持DER编码、密码保护的文件
+
+var ErrP12Unsupported = errors.New("pkcs12: unsupported format")
+
+var (
+	oidDataContentType          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedDataContentType = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+
+	oidKeyBag              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+	oidPKCS8ShroudedKeyBag = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertBag             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidCertTypeX509        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	oidLocalKeyID          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+
+	oidSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+
+	oidPBEWithSHAAnd3KeyTripleDESCBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidPBEWithSHAAnd128BitRC2CBC     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 5}
+	oidPBEWithSHAAnd40BitRC2CBC      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 6}
+	oidPBES2                         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2                        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHmacWithSHA1                  = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHmacWithSHA256                = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHmacWithSHA512                = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAES128CBC                     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC                     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC                     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+type pfxPdu struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+}
+
+type encryptedData struct {
+	Version              int
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"tag:0,optional"`
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type safeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue  `asn1:"tag:0,explicit"`
+	Attributes []p12Attribute `asn1:"set,optional"`
+}
+
+type p12Attribute struct {
+	ID    asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+type certBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Data      []byte
+}
+
+type pbeParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+type pbes2Params struct {
+	Kdf              pkix.AlgorithmIdentifier
+	EncryptionScheme pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt       asn1.RawValue
+	Iterations int
+	KeyLength  int                      `asn1:"optional"`
+	Prf        pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// p12Key SafeBag中的私钥, id为其localKeyId属性
+type p12Key struct {
+	id  string
+	key crypto.PrivateKey
+}
+
+// p12Cert SafeBag中的证书, id为其localKeyId属性, 没有该属性时为空
+type p12Cert struct {
+	id   string
+	cert *x509.Certificate
+}
+
+// p12Password 同一个密码的两种编码, PKCS#12的KDF使用BMPString, PBES2使用原始的UTF-8
+type p12Password struct {
+	bmp  []byte
+	utf8 []byte
+}
+
+// unmarshalDER 同asn1.Unmarshal, 但不允许有多余的数据
+func unmarshalDER(data []byte, out interface{}) error {
+	rest, err := asn1.Unmarshal(data, out)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return errors.New("pkcs12: trailing data found")
+	}
+	return nil
+}
+
+// decodeSafeBags 校验MAC并解密, 返回所有私钥和证书
+func decodeSafeBags(data []byte, password string) ([]p12Key, []p12Cert, error) {
+	var pfx pfxPdu
+	if err := unmarshalDER(data, &pfx); err != nil {
+		return nil, nil, err
+	}
+	if pfx.Version != 3 || !pfx.AuthSafe.ContentType.Equal(oidDataContentType) {
+		return nil, nil, ErrP12Unsupported
+	}
+	var authSafe []byte
+	if err := unmarshalDER(pfx.AuthSafe.Content.Bytes, &authSafe); err != nil {
+		return nil, nil, err
+	}
+
+	bmp, err := bmpString(password)
+	if err != nil {
+		return nil, nil, err
+	}
+	pw := p12Password{bmp: bmp, utf8: []byte(password)}
+	if len(pfx.MacData.Mac.Algorithm.Algorithm) == 0 {
+		if password != "" {
+			return nil, nil, errors.New("pkcs12: no MAC in data")
+		}
+	} else if err = verifyMac(&pfx.MacData, authSafe, pw.bmp); err != nil {
+		// 部分实现对空密码使用空字节而不是只有结束符的BMPString
+		if err != ErrP12Password || password != "" {
+			return nil, nil, err
+		}
+		pw.bmp = nil
+		if err = verifyMac(&pfx.MacData, authSafe, pw.bmp); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var infos []contentInfo
+	if err = unmarshalDER(authSafe, &infos); err != nil {
+		return nil, nil, err
+	}
+	var (
+		keys  []p12Key
+		certs []p12Cert
+	)
+	for _, ci := range infos {
+		var contents []byte
+		switch {
+		case ci.ContentType.Equal(oidDataContentType):
+			if err = unmarshalDER(ci.Content.Bytes, &contents); err != nil {
+				return nil, nil, err
+			}
+		case ci.ContentType.Equal(oidEncryptedDataContentType):
+			var ed encryptedData
+			if err = unmarshalDER(ci.Content.Bytes, &ed); err != nil {
+				return nil, nil, err
+			}
+			info := ed.EncryptedContentInfo
+			if contents, err = pbDecrypt(info.ContentEncryptionAlgorithm, info.EncryptedContent, pw); err != nil {
+				return nil, nil, err
+			}
+		default:
+			return nil, nil, ErrP12Unsupported
+		}
+
+		var bags []safeBag
+		if err = unmarshalDER(contents, &bags); err != nil {
+			return nil, nil, err
+		}
+		for i := range bags {
+			bag := &bags[i]
+			id, err := localKeyID(bag)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch {
+			case bag.ID.Equal(oidKeyBag):
+				key, err := x509.ParsePKCS8PrivateKey(bag.Value.Bytes)
+				if err != nil {
+					return nil, nil, err
+				}
+				keys = append(keys, p12Key{id: id, key: key})
+			case bag.ID.Equal(oidPKCS8ShroudedKeyBag):
+				var info encryptedPrivateKeyInfo
+				if err = unmarshalDER(bag.Value.Bytes, &info); err != nil {
+					return nil, nil, err
+				}
+				der, err := pbDecrypt(info.Algorithm, info.Data, pw)
+				if err != nil {
+					return nil, nil, err
+				}
+				key, err := x509.ParsePKCS8PrivateKey(der)
+				if err != nil {
+					return nil, nil, err
+				}
+				keys = append(keys, p12Key{id: id, key: key})
+			case bag.ID.Equal(oidCertBag):
+				var cb certBag
+				if err = unmarshalDER(bag.Value.Bytes, &cb); err != nil {
+					return nil, nil, err
+				}
+				if !cb.ID.Equal(oidCertTypeX509) {
+					continue
+				}
+				cert, err := x509.ParseCertificate(cb.Data)
+				if err != nil {
+					return nil, nil, err
+				}
+				certs = append(certs, p12Cert{id: id, cert: cert})
+			}
+		}
+	}
+	return keys, certs, nil
+}
+
+func localKeyID(bag *safeBag) (string, error) {
+	for _, attr := range bag.Attributes {
+		if !attr.ID.Equal(oidLocalKeyID) {
+			continue
+		}
+		var id []byte
+		if err := unmarshalDER(attr.Value.Bytes, &id); err != nil {
+			return "", err
+		}
+		return string(id), nil
+	}
+	return "", nil
+}
+
+// bmpString 将s编码为以0结尾的BMPString(UCS-2)
+func bmpString(s string) ([]byte, error) {
+	b := make([]byte, 0, 2*len(s)+2)
+	for _, r := range s {
+		if utf16.IsSurrogate(r) || r > 0xffff {
+			return nil, errors.New("pkcs12: password contains characters that cannot be encoded in UCS-2")
+		}
+		b = append(b, byte(r>>8), byte(r))
+	}
+	return append(b, 0, 0), nil
+}
+
+func verifyMac(md *macData, message, password []byte) error {
+	var (
+		h    func() hash.Hash
+		v    = 64
+		iter = md.Iterations
+	)
+	switch alg := md.Mac.Algorithm.Algorithm; {
+	case alg.Equal(oidSHA1):
+		h = sha1.New
+	case alg.Equal(oidSHA256):
+		h = sha256.New
+	case alg.Equal(oidSHA512):
+		h, v = sha512.New, 128
+	default:
+		return fmt.Errorf("%w: MAC algorithm %s", ErrP12Unsupported, alg)
+	}
+	key := p12KDF(h, v, md.MacSalt, password, iter, 3, h().Size())
+	mac := hmac.New(h, key)
+	mac.Write(message)
+	if !hmac.Equal(mac.Sum(nil), md.Mac.Digest) {
+		return ErrP12Password
+	}
+	return nil
+}
+
+// p12KDF RFC 7292附录B.2中的密钥派生函数, v为哈希函数的分组长度
+func p12KDF(h func() hash.Hash, v int, salt, password []byte, iterations int, id byte, size int) []byte {
+	fill := func(b []byte) []byte {
+		if len(b) == 0 {
+			return nil
+		}
+		n := v * ((len(b) + v - 1) / v)
+		out := make([]byte, n)
+		for i := range out {
+			out[i] = b[i%len(b)]
+		}
+		return out
+	}
+
+	var (
+		d   = bytes.Repeat([]byte{id}, v)
+		in  = append(fill(salt), fill(password)...)
+		out = make([]byte, 0, size+h().Size())
+	)
+	for len(out) < size {
+		hh := h()
+		hh.Write(d)
+		hh.Write(in)
+		a := hh.Sum(nil)
+		for i := 1; i < iterations; i++ {
+			hh.Reset()
+			hh.Write(a)
+			a = hh.Sum(a[:0])
+		}
+		out = append(out, a...)
+		if len(out) >= size {
+			break
+		}
+
+		// I_j = (I_j + B + 1) mod 2^v
+		b := make([]byte, v)
+		for i := range b {
+			b[i] = a[i%len(a)]
+		}
+		for j := 0; j < len(in); j += v {
+			carry := 1
+			for k := v - 1; k >= 0; k-- {
+				sum := int(in[j+k]) + int(b[k]) + carry
+				in[j+k] = byte(sum)
+				carry = sum >> 8
+			}
+		}
+	}
+	return out[:size]
+}
+
+// pbDecrypt 使用基于密码的加密算法解密, 并去掉PKCS#7填充
+func pbDecrypt(alg pkix.AlgorithmIdentifier, data []byte, password p12Password) ([]byte, error) {
+	block, iv, err := pbCipher(alg, password)
+	if err != nil {
+		return nil, err
+	}
+	size := block.BlockSize()
+	if len(data) == 0 || len(data)%size != 0 {
+		return nil, errors.New("pkcs12: invalid encrypted data length")
+	}
+	out := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, data)
+
+	pad := int(out[len(out)-1])
+	if pad == 0 || pad > size || pad > len(out) {
+		return nil, ErrP12Password
+	}
+	for _, c := range out[len(out)-pad:] {
+		if int(c) != pad {
+			return nil, ErrP12Password
+		}
+	}
+	return out[:len(out)-pad], nil
+}
+
+func pbCipher(alg pkix.AlgorithmIdentifier, password p12Password) (cipher.Block, []byte, error) {
+	if alg.Algorithm.Equal(oidPBES2) {
+		return pbes2Cipher(alg, password.utf8)
+	}
+
+	var params pbeParams
+	if err := unmarshalDER(alg.Parameters.FullBytes, &params); err != nil {
+		return nil, nil, err
+	}
+	derive := func(id byte, size int) []byte {
+		return p12KDF(sha1.New, 64, params.Salt, password.bmp, params.Iterations, id, size)
+	}
+	switch {
+	case alg.Algorithm.Equal(oidPBEWithSHAAnd3KeyTripleDESCBC):
+		block, err := des.NewTripleDESCipher(derive(1, 24))
+		return block, derive(2, 8), err
+	case alg.Algorithm.Equal(oidPBEWithSHAAnd128BitRC2CBC):
+		return newRC2(derive(1, 16), 128), derive(2, 8), nil
+	case alg.Algorithm.Equal(oidPBEWithSHAAnd40BitRC2CBC):
+		return newRC2(derive(1, 5), 40), derive(2, 8), nil
+	default:
+		return nil, nil, fmt.Errorf("%w: encryption algorithm %s", ErrP12Unsupported, alg.Algorithm)
+	}
+}
+
+func pbes2Cipher(alg pkix.AlgorithmIdentifier, password []byte) (cipher.Block, []byte, error) {
+	var params pbes2Params
+	if err := unmarshalDER(alg.Parameters.FullBytes, &params); err != nil {
+		return nil, nil, err
+	}
+	if !params.Kdf.Algorithm.Equal(oidPBKDF2) {
+		return nil, nil, fmt.Errorf("%w: PBES2 KDF %s", ErrP12Unsupported, params.Kdf.Algorithm)
+	}
+	var kdf pbkdf2Params
+	if err := unmarshalDER(params.Kdf.Parameters.FullBytes, &kdf); err != nil {
+		return nil, nil, err
+	}
+	if kdf.Salt.Tag != asn1.TagOctetString {
+		return nil, nil, fmt.Errorf("%w: PBKDF2 salt source", ErrP12Unsupported)
+	}
+
+	var prf func() hash.Hash
+	switch {
+	case len(kdf.Prf.Algorithm) == 0, kdf.Prf.Algorithm.Equal(oidHmacWithSHA1):
+		prf = sha1.New
+	case kdf.Prf.Algorithm.Equal(oidHmacWithSHA256):
+		prf = sha256.New
+	case kdf.Prf.Algorithm.Equal(oidHmacWithSHA512):
+		prf = sha512.New
+	default:
+		return nil, nil, fmt.Errorf("%w: PBKDF2 PRF %s", ErrP12Unsupported, kdf.Prf.Algorithm)
+	}
+
+	var keyLen int
+	switch scheme := params.EncryptionScheme.Algorithm; {
+	case scheme.Equal(oidAES128CBC):
+		keyLen = 16
+	case scheme.Equal(oidAES192CBC):
+		keyLen = 24
+	case scheme.Equal(oidAES256CBC):
+		keyLen = 32
+	default:
+		return nil, nil, fmt.Errorf("%w: PBES2 encryption scheme %s", ErrP12Unsupported, scheme)
+	}
+
+	block, err := aes.NewCipher(pbkdf2.Key(password, kdf.Salt.Bytes, kdf.Iterations, keyLen, prf))
+	if err != nil {
+		return nil, nil, err
+	}
+	var iv []byte
+	if err = unmarshalDER(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, nil, err
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, nil, errors.New("pkcs12: invalid PBES2 IV length")
+	}
+	return block, iv, nil
+}
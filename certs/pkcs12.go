@@ -0,0 +1,233 @@
+package certs
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+var (
+	ErrNoPrivateKey      = errors.New("no private key")
+	ErrKeyMismatch       = errors.New("private key does not match any certificate")
+	ErrUnsupportedKey    = errors.New("unsupported private key type")
+	ErrEmptyCertificates = errors.New("empty certificate chain")
+
+	// ErrP12Password 密码错误, DecodeP12和DecodeP12All都返回该错误
+	ErrP12Password = pkcs12.ErrIncorrectPassword
+)
+
+// go-pkcs12只支持一个私钥, 遇到多个私钥时返回该错误
+const errMultipleKeyBags = "pkcs12: expected exactly one key bag"
+
+// multipleKeyBags 判断err是否由包含多个私钥的文件导致, 只有这种情况才需要自行解析SafeBag;
+// 多个私钥的文件通常有两个以上的SafeContents, go-pkcs12会在解密前返回NotImplementedError
+func multipleKeyBags(err error) bool {
+	var notImplemented pkcs12.NotImplementedError
+	return err.Error() == errMultipleKeyBags || errors.As(err, &notImplemented)
+}
+
+// P12Bundle PKCS#12文件中的私钥及其证书链
+type P12Bundle struct {
+	PrivateKey crypto.PrivateKey   // 私钥
+	Leaf       *x509.Certificate   // 与私钥对应的证书
+	CACerts    []*x509.Certificate // 其余的证书, 按照从Leaf的签发者到根证书的顺序排列
+}
+
+// TLSCertificate 转换为tls.Certificate, 证书链中包含CACerts
+func (b *P12Bundle) TLSCertificate() tls.Certificate {
+	cert := tls.Certificate{
+		Certificate: [][]byte{b.Leaf.Raw},
+		PrivateKey:  b.PrivateKey,
+		Leaf:        b.Leaf,
+	}
+	for _, ca := range b.CACerts {
+		cert.Certificate = append(cert.Certificate, ca.Raw)
+	}
+	return cert
+}
+
+// LoadP12 读取并解码PKCS#12文件
+func LoadP12(p12Path string, password string) (*P12Bundle, error) {
+	if p12Path == "" {
+		return nil, errors.New("empty path")
+	}
+	data, err := ioutil.ReadFile(p12Path)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeP12(data, password)
+}
+
+// DecodeP12 解码只包含一个私钥的PKCS#12数据, 支持证书链以及使用AES(PBES2)加密的文件
+func DecodeP12(data []byte, password string) (*P12Bundle, error) {
+	key, cert, caCerts, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		return nil, err
+	}
+	return newP12Bundle(key, append([]*x509.Certificate{cert}, caCerts...))
+}
+
+// DecodeP12All 解码包含一个或多个私钥的PKCS#12数据, 私钥和证书通过localKeyId属性进行配对,
+// 每个私钥的CACerts中只包含能与其证书连成证书链的证书
+func DecodeP12All(data []byte, password string) ([]*P12Bundle, error) {
+	bundle, err := DecodeP12(data, password)
+	if err == nil {
+		bundle.CACerts = linkChain(bundle.Leaf, bundle.CACerts)
+		return []*P12Bundle{bundle}, nil
+	}
+	if !multipleKeyBags(err) {
+		return nil, err
+	}
+
+	keys, bags, err := decodeSafeBags(data, password)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, ErrNoPrivateKey
+	}
+	certs := make([]*x509.Certificate, 0, len(bags))
+	for _, bag := range bags {
+		certs = append(certs, bag.cert)
+	}
+
+	bundles := make([]*P12Bundle, 0, len(keys))
+	for _, key := range keys {
+		candidates := certs
+		if key.id != "" {
+			for _, bag := range bags {
+				if bag.id == key.id {
+					candidates = append([]*x509.Certificate{bag.cert}, certs...)
+					break
+				}
+			}
+		}
+		leaf, others, err := findLeaf(key.key, candidates)
+		if err != nil {
+			return nil, err
+		}
+		bundles = append(bundles, &P12Bundle{
+			PrivateKey: key.key,
+			Leaf:       leaf,
+			CACerts:    linkChain(leaf, others),
+		})
+	}
+	return bundles, nil
+}
+
+// EncodeP12 将证书(包括证书链)和私钥编码为使用AES-256和SHA-256保护的PKCS#12数据
+func EncodeP12(cert tls.Certificate, password string) ([]byte, error) {
+	return encodeP12(pkcs12.Modern2023, cert, password)
+}
+
+// EncodeP12Legacy 同EncodeP12, 但使用3DES和SHA-1, 用于兼容不支持AES的旧软件
+func EncodeP12Legacy(cert tls.Certificate, password string) ([]byte, error) {
+	return encodeP12(pkcs12.LegacyDES, cert, password)
+}
+
+func encodeP12(encoder *pkcs12.Encoder, cert tls.Certificate, password string) ([]byte, error) {
+	if len(cert.Certificate) == 0 {
+		return nil, ErrEmptyCertificates
+	}
+	if cert.PrivateKey == nil {
+		return nil, ErrNoPrivateKey
+	}
+	certs := make([]*x509.Certificate, 0, len(cert.Certificate))
+	for _, raw := range cert.Certificate {
+		c, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, c)
+	}
+	return encoder.Encode(cert.PrivateKey, certs[0], certs[1:], password)
+}
+
+// newP12Bundle 从certs中找到与key对应的证书作为Leaf, 其余证书按照签发顺序排列
+func newP12Bundle(key crypto.PrivateKey, certs []*x509.Certificate) (*P12Bundle, error) {
+	leaf, others, err := findLeaf(key, certs)
+	if err != nil {
+		return nil, err
+	}
+	return &P12Bundle{
+		PrivateKey: key,
+		Leaf:       leaf,
+		CACerts:    sortChain(leaf, others),
+	}, nil
+}
+
+// findLeaf 从certs中找到与key对应的证书, 返回该证书和其余去重后的证书
+func findLeaf(key crypto.PrivateKey, certs []*x509.Certificate) (leaf *x509.Certificate, others []*x509.Certificate, err error) {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, nil, ErrUnsupportedKey
+	}
+	public, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok {
+		return nil, nil, ErrUnsupportedKey
+	}
+
+	for _, cert := range certs {
+		if public.Equal(cert.PublicKey) {
+			leaf = cert
+			break
+		}
+	}
+	if leaf == nil {
+		return nil, nil, ErrKeyMismatch
+	}
+	for _, cert := range certs {
+		if cert != leaf && !cert.Equal(leaf) && !containsCert(others, cert) {
+			others = append(others, cert)
+		}
+	}
+	return leaf, others, nil
+}
+
+// sortChain 将certs按照从leaf的签发者开始的顺序排列, 不在链上的证书放在最后
+func sortChain(leaf *x509.Certificate, certs []*x509.Certificate) []*x509.Certificate {
+	sorted := linkChain(leaf, certs)
+	for _, cert := range certs {
+		if !containsCert(sorted, cert) {
+			sorted = append(sorted, cert)
+		}
+	}
+	return sorted
+}
+
+// linkChain 返回certs中从leaf的签发者开始依次签发的证书
+func linkChain(leaf *x509.Certificate, certs []*x509.Certificate) []*x509.Certificate {
+	var (
+		chain   []*x509.Certificate
+		used    = make([]bool, len(certs))
+		current = leaf
+	)
+	for {
+		next := -1
+		for i, cert := range certs {
+			if !used[i] && current.CheckSignatureFrom(cert) == nil {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return chain
+		}
+		used[next] = true
+		chain = append(chain, certs[next])
+		current = certs[next]
+	}
+}
+
+func containsCert(certs []*x509.Certificate, cert *x509.Certificate) bool {
+	for _, c := range certs {
+		if c.Equal(cert) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package certs
+
+import (
+	"crypto/tls"
+	"errors"
+	"os"
+	"testing"
+)
+
+// testdata中的文件由OpenSSL 3生成, 密码均为pw:
+// single.p12: 默认格式(PBES2 AES-256, SHA-256 MAC), 包含a及其签发者ca
+// legacy.p12: -legacy格式(RC2-40, 3DES, SHA-1 MAC), 包含b以及与其无关的自签名证书unrelated
+// multi.p12: 将以上两个文件的SafeContents合并后重新计算MAC, 包含两个私钥
+
+const testP12Password = "pw"
+
+func readP12(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func subjects(bundle *P12Bundle) []string {
+	names := []string{bundle.Leaf.Subject.CommonName}
+	for _, ca := range bundle.CACerts {
+		names = append(names, ca.Subject.CommonName)
+	}
+	return names
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDecodeP12(t *testing.T) {
+	cases := []struct {
+		file string
+		want []string
+	}{
+		{"single.p12", []string{"a", "ca"}},
+		{"legacy.p12", []string{"b", "unrelated"}},
+	}
+	for _, c := range cases {
+		bundle, err := DecodeP12(readP12(t, c.file), testP12Password)
+		if err != nil {
+			t.Fatalf("%s: %v", c.file, err)
+		}
+		if got := subjects(bundle); !equalStrings(got, c.want) {
+			t.Fatalf("%s: got %v, want %v", c.file, got, c.want)
+		}
+	}
+}
+
+func TestDecodeP12All(t *testing.T) {
+	cases := []struct {
+		file string
+		want [][]string
+	}{
+		{"single.p12", [][]string{{"a", "ca"}}},
+		{"legacy.p12", [][]string{{"b"}}},
+		{"multi.p12", [][]string{{"a", "ca"}, {"b", "ca"}}},
+	}
+	for _, c := range cases {
+		bundles, err := DecodeP12All(readP12(t, c.file), testP12Password)
+		if err != nil {
+			t.Fatalf("%s: %v", c.file, err)
+		}
+		if len(bundles) != len(c.want) {
+			t.Fatalf("%s: got %d bundles, want %d", c.file, len(bundles), len(c.want))
+		}
+		for i, bundle := range bundles {
+			if got := subjects(bundle); !equalStrings(got, c.want[i]) {
+				t.Fatalf("%s: bundle %d got %v, want %v", c.file, i, got, c.want[i])
+			}
+		}
+	}
+}
+
+func TestDecodeP12WrongPassword(t *testing.T) {
+	for _, file := range []string{"single.p12", "legacy.p12", "multi.p12"} {
+		data := readP12(t, file)
+		if _, err := DecodeP12(data, "wrong"); !errors.Is(err, ErrP12Password) {
+			t.Fatalf("%s: DecodeP12 got %v, want ErrP12Password", file, err)
+		}
+		if _, err := DecodeP12All(data, "wrong"); !errors.Is(err, ErrP12Password) {
+			t.Fatalf("%s: DecodeP12All got %v, want ErrP12Password", file, err)
+		}
+	}
+}
+
+func TestEncodeP12(t *testing.T) {
+	bundle, err := DecodeP12(readP12(t, "single.p12"), testP12Password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, encode := range map[string]func(tls.Certificate, string) ([]byte, error){
+		"EncodeP12":       EncodeP12,
+		"EncodeP12Legacy": EncodeP12Legacy,
+	} {
+		data, err := encode(bundle.TLSCertificate(), testP12Password)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		decoded, err := DecodeP12(data, testP12Password)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := subjects(decoded); !equalStrings(got, []string{"a", "ca"}) {
+			t.Fatalf("%s: got %v", name, got)
+		}
+	}
+}
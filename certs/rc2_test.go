@@ -0,0 +1,36 @@
+package certs
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// RFC 2268第5节中的测试向量
+func TestRC2(t *testing.T) {
+	cases := []struct {
+		key, plain, cipher string
+		bits               int
+	}{
+		{"0000000000000000", "0000000000000000", "ebb773f993278eff", 63},
+		{"ffffffffffffffff", "ffffffffffffffff", "278b27e42e2f0d49", 64},
+		{"3000000000000000", "1000000000000001", "30649edf9be7d2c2", 64},
+		{"88", "0000000000000000", "61a8a244adacccf0", 64},
+		{"88bca90e90875a7f0f79c384627bafb2", "0000000000000000", "2269552ab0f85ca6", 128},
+	}
+	for _, c := range cases {
+		key, _ := hex.DecodeString(c.key)
+		plain, _ := hex.DecodeString(c.plain)
+		want, _ := hex.DecodeString(c.cipher)
+		block := newRC2(key, c.bits)
+		got := make([]byte, len(plain))
+		block.Encrypt(got, plain)
+		if !bytes.Equal(got, want) {
+			t.Fatalf("key %s: encrypt got %x, want %x", c.key, got, want)
+		}
+		block.Decrypt(got, got)
+		if !bytes.Equal(got, plain) {
+			t.Fatalf("key %s: decrypt got %x, want %x", c.key, got, plain)
+		}
+	}
+}
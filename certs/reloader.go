@@ -0,0 +1,279 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultReloadInterval = 10 * time.Second
+
+var (
+	ErrNoCertificate = errors.New("no certificate")
+	ErrNoCA          = errors.New("no CA bundle loaded")
+	ErrInvalidCA     = errors.New("no certificates found in CA bundle")
+	ErrNoServerName  = errors.New("no server name to verify, set tls.Config.ServerName when dialing an IP address")
+)
+
+// ReloaderOption Reloader的配置项
+type ReloaderOption func(*Reloader)
+
+// WithCAFile 同时监听CA证书文件, 用于校验对端证书
+func WithCAFile(caFile string) ReloaderOption {
+	return func(r *Reloader) {
+		r.caFile = caFile
+	}
+}
+
+// WithReloadInterval 检查文件是否变化的时间间隔, 默认10秒
+func WithReloadInterval(d time.Duration) ReloaderOption {
+	return func(r *Reloader) {
+		if d > 0 {
+			r.interval = d
+		}
+	}
+}
+
+// WithReloadErrorHandler 重新加载失败时的回调, 失败时会继续使用之前的证书
+func WithReloadErrorHandler(handler func(err error)) ReloaderOption {
+	return func(r *Reloader) {
+		r.onError = handler
+	}
+}
+
+// WithReloadHandler 重新加载成功时的回调
+func WithReloadHandler(handler func()) ReloaderOption {
+	return func(r *Reloader) {
+		r.onReload = handler
+	}
+}
+
+type reloadState struct {
+	cert *tls.Certificate
+	pool *x509.CertPool
+}
+
+// Reloader 定期检查证书、私钥(和CA)文件, 文件变化时重新加载并原子替换, 不需要重启TLS服务端或客户端
+type Reloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+	interval time.Duration
+	onError  func(err error)
+	onReload func()
+
+	state    atomic.Value // *reloadState
+	modTimes map[string]time.Time
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// NewReloader 加载证书和私钥并开始监听文件变化, 首次加载失败时返回错误
+func NewReloader(certFile, keyFile string, opts ...ReloaderOption) (*Reloader, error) {
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: defaultReloadInterval,
+		done:     make(chan struct{}),
+	}
+	for _, op := range opts {
+		op(r)
+	}
+	r.modTimes = r.stat()
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	go r.watch()
+	return r, nil
+}
+
+func (r *Reloader) files() []string {
+	files := []string{r.certFile, r.keyFile}
+	if r.caFile != "" {
+		files = append(files, r.caFile)
+	}
+	return files
+}
+
+func (r *Reloader) stat() map[string]time.Time {
+	modTimes := make(map[string]time.Time)
+	for _, f := range r.files() {
+		if info, err := os.Stat(f); err == nil {
+			modTimes[f] = info.ModTime()
+		}
+	}
+	return modTimes
+}
+
+func (r *Reloader) changed(modTimes map[string]time.Time) bool {
+	for _, f := range r.files() {
+		if !modTimes[f].Equal(r.modTimes[f]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Reloader) watch() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			modTimes := r.stat()
+			if !r.changed(modTimes) {
+				continue
+			}
+			r.modTimes = modTimes
+			if err := r.Reload(); err != nil {
+				if r.onError != nil {
+					r.onError(err)
+				}
+				continue
+			}
+			if r.onReload != nil {
+				r.onReload()
+			}
+		}
+	}
+}
+
+// Reload 立即重新加载所有文件, 失败时保留之前的证书
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return err
+		}
+	}
+	state := &reloadState{cert: &cert}
+	if r.caFile != "" {
+		data, err := ioutil.ReadFile(r.caFile)
+		if err != nil {
+			return err
+		}
+		state.pool = x509.NewCertPool()
+		if !state.pool.AppendCertsFromPEM(data) {
+			return ErrInvalidCA
+		}
+	}
+	r.state.Store(state)
+	return nil
+}
+
+func (r *Reloader) load() *reloadState {
+	return r.state.Load().(*reloadState)
+}
+
+// Certificate 返回当前的证书
+func (r *Reloader) Certificate() *tls.Certificate {
+	return r.load().cert
+}
+
+// CertPool 返回当前的CA证书池, 未指定CA文件时返回nil
+func (r *Reloader) CertPool() *x509.CertPool {
+	return r.load().pool
+}
+
+// GetCertificate 用于服务端tls.Config.GetCertificate
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+// GetClientCertificate 用于客户端tls.Config.GetClientCertificate
+func (r *Reloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+// VerifyPeerCertificate 用于服务端tls.Config.VerifyPeerCertificate, 使用当前的CA证书池校验客户端证书链,
+// 由于tls.Config中的ClientCAs无法热更新, 需要配合RequireAnyClientCert使用
+func (r *Reloader) VerifyPeerCertificate(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		return ErrNoCertificate
+	}
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+		certs = append(certs, cert)
+	}
+	return r.verify(certs, "", x509.ExtKeyUsageClientAuth)
+}
+
+// VerifyConnection 用于客户端tls.Config.VerifyConnection, 使用当前的CA证书池校验服务端证书链和主机名,
+// 由于tls.Config中的RootCAs无法热更新, 需要配合InsecureSkipVerify使用
+func (r *Reloader) VerifyConnection(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return ErrNoCertificate
+	}
+	if cs.ServerName == "" {
+		return ErrNoServerName
+	}
+	return r.verify(cs.PeerCertificates, cs.ServerName, x509.ExtKeyUsageServerAuth)
+}
+
+// verify 校验证书链, dnsName不为空时同时校验主机名
+func (r *Reloader) verify(certs []*x509.Certificate, dnsName string, usage x509.ExtKeyUsage) error {
+	pool := r.CertPool()
+	if pool == nil {
+		return ErrNoCA
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		DNSName:       dnsName,
+		Roots:         pool,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	})
+	return err
+}
+
+// ServerConfig 返回使用Reloader的服务端配置, 指定了CA文件时会要求并校验客户端证书
+func (r *Reloader) ServerConfig() *tls.Config {
+	config := &tls.Config{
+		GetCertificate: r.GetCertificate,
+	}
+	if r.caFile != "" {
+		config.ClientAuth = tls.RequireAnyClientCert
+		config.VerifyPeerCertificate = r.VerifyPeerCertificate
+	}
+	return config
+}
+
+// ClientConfig 返回使用Reloader的客户端配置, 指定了CA文件时使用其校验服务端证书和主机名,
+// 通过IP地址连接时需要设置ServerName
+func (r *Reloader) ClientConfig() *tls.Config {
+	config := &tls.Config{
+		GetClientCertificate: r.GetClientCertificate,
+	}
+	if r.caFile != "" {
+		// 跳过标准校验以便使用热更新的CA, 证书链和主机名由VerifyConnection校验
+		config.InsecureSkipVerify = true
+		config.VerifyConnection = r.VerifyConnection
+	}
+	return config
+}
+
+// Close 停止监听文件变化
+func (r *Reloader) Close() {
+	r.closeOnce.Do(func() {
+		close(r.done)
+	})
+}
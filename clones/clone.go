@@ -1,53 +1,218 @@
 package clones
 
-import "reflect"
+import (
+	"reflect"
+	"sync"
+	"time"
+	"unsafe"
+)
 
-func deepCopy(dst, src reflect.Value) {
-	switch src.Kind() {
+// DeepCopier 自定义深拷贝, 实现了该接口的类型在拷贝时会调用DeepCopy, 返回值的类型必须与接收者相同, 返回nil时拷贝结果为零值
+type DeepCopier interface {
+	DeepCopy() interface{}
+}
+
+var (
+	copiers        sync.Map // reflect.Type -> func(reflect.Value) reflect.Value
+	deepCopierType = reflect.TypeOf((*DeepCopier)(nil)).Elem()
+	timeType       = reflect.TypeOf(time.Time{})
+)
+
+// RegisterCopier 为类型T注册拷贝函数, 拷贝T类型的值时使用fn而不是默认的深拷贝
+func RegisterCopier[T any](fn func(T) T) {
+	copiers.Store(reflect.TypeOf((*T)(nil)).Elem(), func(v reflect.Value) reflect.Value {
+		return reflect.ValueOf(fn(v.Interface().(T)))
+	})
+	resetPlans()
+}
+
+// Option 拷贝选项
+type Option func(*cloner)
+
+// WithNilChannels 拷贝结果中的channel为nil, 默认与原值共享channel
+func WithNilChannels() Option {
+	return func(c *cloner) {
+		c.nilChannels = true
+	}
+}
+
+// visit 已经拷贝过的指针、map或slice, 用于保持共享关系和处理循环引用
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+type cloner struct {
+	visited     map[visit]reflect.Value
+	nilChannels bool
+	emptyNil    bool // nil的map和slice拷贝为空的非nil值, 与DeepCopy、DeepClone原有的行为保持一致
+}
+
+func newCloner(opts ...Option) *cloner {
+	c := &cloner{}
+	for _, op := range opts {
+		op(c)
+	}
+	return c
+}
+
+// exported 去掉通过未导出字段获取的Value的只读限制, v必须可寻址
+func exported(v reflect.Value) reflect.Value {
+	if v.CanInterface() || !v.CanAddr() {
+		return v
+	}
+	return reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
+}
+
+// addressable 返回可寻址的v, 以便访问其未导出的字段
+func addressable(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return exported(v)
+	}
+	tmp := reflect.New(v.Type()).Elem()
+	tmp.Set(v)
+	return tmp
+}
+
+func (c *cloner) remember(key visit, v reflect.Value) {
+	if c.visited == nil {
+		c.visited = make(map[visit]reflect.Value)
+	}
+	c.visited[key] = v
+}
+
+func (c *cloner) copy(dst, src reflect.Value) {
+	c.copyWith(planOf(src.Type()), dst, src)
+}
+
+func (c *cloner) copyWith(p *plan, dst, src reflect.Value) {
+	switch {
+	case p.pod:
+		dst.Set(src)
+		return
+	case p.copier != nil:
+		dst.Set(p.copier(src))
+		return
+	case p.deepCopier:
+		if p.kind == reflect.Ptr && src.IsNil() {
+			return
+		}
+		// DeepCopy返回nil时拷贝结果为零值, 返回的类型不对时panic并给出具体类型, 而不是reflect的报错
+		result := src.Interface().(DeepCopier).DeepCopy()
+		if result == nil {
+			dst.Set(reflect.Zero(p.typ))
+			return
+		}
+		value := reflect.ValueOf(result)
+		if !value.Type().AssignableTo(p.typ) {
+			panic("DeepCopy: " + p.typ.String() + ".DeepCopy returned " + value.Type().String())
+		}
+		dst.Set(value)
+		return
+	}
+
+	switch p.kind {
 	case reflect.Interface:
-		value := src.Elem()
-		if !value.IsValid() {
+		if src.IsNil() {
 			return
 		}
+		value := src.Elem()
 		newValue := reflect.New(value.Type()).Elem()
-		deepCopy(newValue, value)
+		c.copy(newValue, addressable(value))
 		dst.Set(newValue)
 	case reflect.Ptr:
-		value := src.Elem()
-		if !value.IsValid() {
+		if src.IsNil() {
+			return
+		}
+		key := visit{ptr: src.Pointer(), typ: p.typ}
+		if v, ok := c.visited[key]; ok {
+			dst.Set(v)
 			return
 		}
-		dst.Set(reflect.New(value.Type()))
-		deepCopy(dst.Elem(), value)
+		newValue := reflect.New(p.typ.Elem())
+		c.remember(key, newValue)
+		dst.Set(newValue)
+		c.copyWith(p.elem, newValue.Elem(), exported(src.Elem()))
 	case reflect.Map:
-		dst.Set(reflect.MakeMap(src.Type()))
-		keys := src.MapKeys()
-		for _, key := range keys {
-			value := src.MapIndex(key)
-			newValue := reflect.New(value.Type()).Elem()
-			deepCopy(newValue, value)
-			dst.SetMapIndex(key, newValue)
+		if src.IsNil() {
+			if c.emptyNil {
+				dst.Set(reflect.MakeMap(p.typ))
+			}
+			return
+		}
+		key := visit{ptr: src.Pointer(), typ: p.typ}
+		if v, ok := c.visited[key]; ok {
+			dst.Set(v)
+			return
+		}
+		newMap := reflect.MakeMapWithSize(p.typ, src.Len())
+		c.remember(key, newMap)
+		dst.Set(newMap)
+		iter := src.MapRange()
+		for iter.Next() {
+			newKey := reflect.New(p.key.typ).Elem()
+			c.copyWith(p.key, newKey, addressable(iter.Key()))
+			newValue := reflect.New(p.elem.typ).Elem()
+			c.copyWith(p.elem, newValue, addressable(iter.Value()))
+			newMap.SetMapIndex(newKey, newValue)
 		}
 	case reflect.Slice:
-		dst.Set(reflect.MakeSlice(src.Type(), src.Len(), src.Cap()))
+		if src.IsNil() {
+			if c.emptyNil {
+				dst.Set(reflect.MakeSlice(p.typ, 0, 0))
+			}
+			return
+		}
+		key := visit{ptr: src.Pointer(), typ: p.typ, len: src.Len()}
+		if v, ok := c.visited[key]; ok {
+			dst.Set(v)
+			return
+		}
+		newSlice := reflect.MakeSlice(p.typ, src.Len(), src.Cap())
+		c.remember(key, newSlice)
+		dst.Set(newSlice)
+		if p.elem.pod {
+			reflect.Copy(newSlice, src)
+			return
+		}
+		for i := 0; i < src.Len(); i++ {
+			c.copyWith(p.elem, newSlice.Index(i), src.Index(i))
+		}
+	case reflect.Array:
 		for i := 0; i < src.Len(); i++ {
-			deepCopy(dst.Index(i), src.Index(i))
+			c.copyWith(p.elem, dst.Index(i), exported(src.Index(i)))
 		}
 	case reflect.Struct:
-		typeSrc := src.Type()
-		for i := 0; i < src.NumField(); i++ {
-			value := src.Field(i)
-			tag := typeSrc.Field(i).Tag
-			if value.CanSet() && tag.Get("deepcopy") != "-" {
-				deepCopy(dst.Field(i), value)
+		for _, f := range p.fields {
+			df, sf := dst.Field(f.index), src.Field(f.index)
+			if !f.exported {
+				df, sf = exported(df), exported(sf)
 			}
+			c.copyWith(f.plan, df, sf)
+		}
+	case reflect.Chan:
+		if !c.nilChannels {
+			dst.Set(src)
 		}
 	default:
 		dst.Set(src)
 	}
 }
 
-// DeepCopy 深拷贝
+// Clone 深拷贝v, 支持循环引用并保持指针之间的共享关系, 未导出的字段同样会被拷贝,
+// 带有`deepcopy:"-"`标签的字段会被忽略, time.Time、func和channel按值拷贝, nil的map和slice拷贝结果仍为nil
+func Clone[T any](v T, opts ...Option) T {
+	var (
+		result T
+		src    = reflect.ValueOf(&v).Elem()
+		dst    = reflect.ValueOf(&result).Elem()
+	)
+	newCloner(opts...).copy(dst, src)
+	return result
+}
+
+// DeepCopy 深拷贝, channel与原值共享, nil的map和slice拷贝为空的非nil值
 func DeepCopy(dst, src interface{}) {
 	typeDst := reflect.TypeOf(dst)
 	typeSrc := reflect.TypeOf(src)
@@ -64,12 +229,19 @@ func DeepCopy(dst, src interface{}) {
 		panic("DeepCopy: invalid arguments")
 	}
 
-	deepCopy(valueDst, valueSrc)
+	c := newCloner()
+	c.emptyNil = true
+	c.remember(visit{ptr: reflect.ValueOf(src).Pointer(), typ: typeSrc}, reflect.ValueOf(dst))
+	c.copy(valueDst, valueSrc)
 }
 
-// DeepClone 深克隆
+// DeepClone 深克隆, 与DeepCopy的行为一致
 func DeepClone(v interface{}) interface{} {
 	dst := reflect.New(reflect.TypeOf(v)).Elem()
-	deepCopy(dst, reflect.ValueOf(v))
+	src := reflect.New(reflect.TypeOf(v)).Elem()
+	src.Set(reflect.ValueOf(v))
+	c := newCloner()
+	c.emptyNil = true
+	c.copy(dst, src)
 	return dst.Interface()
 }
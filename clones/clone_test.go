@@ -0,0 +1,123 @@
+package clones
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// 对比按类型缓存拷贝计划的Clone/DeepCopy与原来每次都递归反射的实现
+
+type Item struct {
+	Id    int64
+	Count int32
+	Attrs [4]float64
+}
+
+type Player struct {
+	Id     int64
+	Name   string
+	Gold   int64
+	Pos    [3]float32
+	Items  []Item
+	Avatar []byte
+	Tags   map[string]string
+	Cache  interface{} `deepcopy:"-"`
+}
+
+type GameState struct {
+	RoomId  int64
+	Round   int32
+	Players []*Player
+	Board   [64]int8
+}
+
+func newGameState() *GameState {
+	s := &GameState{RoomId: 1, Round: 10}
+	for i := 0; i < 8; i++ {
+		p := &Player{
+			Id:     int64(i),
+			Name:   fmt.Sprintf("player-%d", i),
+			Gold:   int64(i * 100),
+			Avatar: make([]byte, 256),
+			Tags:   map[string]string{"vip": "1"},
+		}
+		for j := 0; j < 16; j++ {
+			p.Items = append(p.Items, Item{Id: int64(j), Count: int32(j)})
+		}
+		s.Players = append(s.Players, p)
+	}
+	return s
+}
+
+// legacyDeepCopy 原来的递归实现
+func legacyDeepCopy(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Interface:
+		value := src.Elem()
+		if !value.IsValid() {
+			return
+		}
+		newValue := reflect.New(value.Type()).Elem()
+		legacyDeepCopy(newValue, value)
+		dst.Set(newValue)
+	case reflect.Ptr:
+		value := src.Elem()
+		if !value.IsValid() {
+			return
+		}
+		dst.Set(reflect.New(value.Type()))
+		legacyDeepCopy(dst.Elem(), value)
+	case reflect.Map:
+		dst.Set(reflect.MakeMap(src.Type()))
+		keys := src.MapKeys()
+		for _, key := range keys {
+			value := src.MapIndex(key)
+			newValue := reflect.New(value.Type()).Elem()
+			legacyDeepCopy(newValue, value)
+			dst.SetMapIndex(key, newValue)
+		}
+	case reflect.Slice:
+		dst.Set(reflect.MakeSlice(src.Type(), src.Len(), src.Cap()))
+		for i := 0; i < src.Len(); i++ {
+			legacyDeepCopy(dst.Index(i), src.Index(i))
+		}
+	case reflect.Struct:
+		typeSrc := src.Type()
+		for i := 0; i < src.NumField(); i++ {
+			value := src.Field(i)
+			tag := typeSrc.Field(i).Tag
+			if value.CanSet() && tag.Get("deepcopy") != "-" {
+				legacyDeepCopy(dst.Field(i), value)
+			}
+		}
+	default:
+		dst.Set(src)
+	}
+}
+
+func BenchmarkLegacy(b *testing.B) {
+	state := newGameState()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var dst GameState
+		legacyDeepCopy(reflect.ValueOf(&dst).Elem(), reflect.ValueOf(state).Elem())
+	}
+}
+
+func BenchmarkDeepCopy(b *testing.B) {
+	state := newGameState()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var dst GameState
+		DeepCopy(&dst, state)
+	}
+}
+
+func BenchmarkClone(b *testing.B) {
+	state := newGameState()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = Clone(state)
+	}
+}
@@ -0,0 +1,399 @@
+package clones
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidPath    = errors.New("invalid change path")
+	ErrNotPointer     = errors.New("dst must be a non-nil pointer")
+	ErrIndexOutRange  = errors.New("index out of range")
+	ErrUnsupportedKey = errors.New("unsupported map key type")
+)
+
+// ChangeType 变化的类型
+type ChangeType int
+
+const (
+	Modified ChangeType = iota // 修改
+	Added                      // map中新增的key
+	Removed                    // map中删除的key
+)
+
+func (t ChangeType) String() string {
+	switch t {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	default:
+		return "modified"
+	}
+}
+
+// Change 某个字段的变化, Path形如: Players[3].Gold、Tags["vip"]
+type Change struct {
+	Path string
+	Type ChangeType
+	From interface{}
+	To   interface{}
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %v -> %v", c.Path, c.From, c.To)
+}
+
+// Diff 比较a和b, 返回b相对于a的变化, 带有`deepcopy:"-"`标签的字段会被忽略,
+// slice长度不同时整个slice作为一个变化, 注册了拷贝函数或实现了DeepCopier的类型作为整体比较
+func Diff[T any](a, b T) []Change {
+	d := &differ{
+		visited: make(map[diffVisit]bool),
+	}
+	d.diff("", reflect.ValueOf(&a).Elem(), reflect.ValueOf(&b).Elem())
+	return d.changes
+}
+
+type diffVisit struct {
+	a, b uintptr
+	typ  reflect.Type
+}
+
+type differ struct {
+	changes []Change
+	visited map[diffVisit]bool
+}
+
+func (d *differ) add(path string, typ ChangeType, a, b reflect.Value) {
+	change := Change{Path: path, Type: typ}
+	if a.IsValid() {
+		change.From = Clone(a.Interface())
+	}
+	if b.IsValid() {
+		change.To = Clone(b.Interface())
+	}
+	d.changes = append(d.changes, change)
+}
+
+func (d *differ) diff(path string, a, b reflect.Value) {
+	p := planOf(a.Type())
+	if p.kind == reflect.Func {
+		// func不可比较, 忽略
+		return
+	}
+	if p.copier != nil || p.deepCopier || (p.pod && p.kind != reflect.Struct && p.kind != reflect.Array) {
+		if !equal(a, b) {
+			d.add(path, Modified, a, b)
+		}
+		return
+	}
+
+	switch p.kind {
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() || a.Elem().Type() != b.Elem().Type() {
+			if !equal(a, b) {
+				d.add(path, Modified, a, b)
+			}
+			return
+		}
+		d.diff(path, addressable(a.Elem()), addressable(b.Elem()))
+	case reflect.Ptr:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				d.add(path, Modified, a, b)
+			}
+			return
+		}
+		key := diffVisit{a: a.Pointer(), b: b.Pointer(), typ: p.typ}
+		if d.visited[key] {
+			return
+		}
+		d.visited[key] = true
+		d.diff(path, exported(a.Elem()), exported(b.Elem()))
+	case reflect.Map:
+		if a.IsNil() != b.IsNil() {
+			d.add(path, Modified, a, b)
+			return
+		}
+		for _, key := range sortedKeys(a) {
+			keyPath := path + "[" + formatKey(key) + "]"
+			av, bv := a.MapIndex(key), b.MapIndex(key)
+			if !bv.IsValid() {
+				d.add(keyPath, Removed, av, reflect.Value{})
+				continue
+			}
+			d.diff(keyPath, addressable(av), addressable(bv))
+		}
+		for _, key := range sortedKeys(b) {
+			if !a.MapIndex(key).IsValid() {
+				d.add(path+"["+formatKey(key)+"]", Added, reflect.Value{}, b.MapIndex(key))
+			}
+		}
+	case reflect.Slice:
+		if a.IsNil() != b.IsNil() || a.Len() != b.Len() {
+			d.add(path, Modified, a, b)
+			return
+		}
+		for i := 0; i < a.Len(); i++ {
+			d.diff(path+"["+strconv.Itoa(i)+"]", a.Index(i), b.Index(i))
+		}
+	case reflect.Array:
+		if p.pod && equal(a, b) {
+			return
+		}
+		for i := 0; i < a.Len(); i++ {
+			d.diff(path+"["+strconv.Itoa(i)+"]", exported(a.Index(i)), exported(b.Index(i)))
+		}
+	case reflect.Struct:
+		if p.typ == timeType {
+			if !equal(a, b) {
+				d.add(path, Modified, a, b)
+			}
+			return
+		}
+		if p.pod && equal(a, b) {
+			return
+		}
+		for _, f := range p.fields {
+			fieldPath := p.typ.Field(f.index).Name
+			if path != "" {
+				fieldPath = path + "." + fieldPath
+			}
+			d.diff(fieldPath, exported(a.Field(f.index)), exported(b.Field(f.index)))
+		}
+	default:
+		if !equal(a, b) {
+			d.add(path, Modified, a, b)
+		}
+	}
+}
+
+func equal(a, b reflect.Value) bool {
+	if t, ok := a.Interface().(time.Time); ok {
+		return t.Equal(b.Interface().(time.Time))
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// sortedKeys 按照格式化后的字符串排序map的key, 使Diff的结果稳定
+func sortedKeys(m reflect.Value) []reflect.Value {
+	keys := m.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return formatKey(keys[i]) < formatKey(keys[j])
+	})
+	return keys
+}
+
+func formatKey(key reflect.Value) string {
+	if key.Kind() == reflect.String {
+		return strconv.Quote(key.String())
+	}
+	return fmt.Sprint(key.Interface())
+}
+
+// Apply 将changes依次应用到dst上, dst必须为指针, 路径中遇到nil指针或nil map时会自动创建
+func Apply(dst interface{}, changes []Change) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return ErrNotPointer
+	}
+	for _, change := range changes {
+		steps, err := parsePath(change.Path)
+		if err != nil {
+			return err
+		}
+		if err = apply(v.Elem(), steps, change); err != nil {
+			return fmt.Errorf("apply %s: %w", change.Path, err)
+		}
+	}
+	return nil
+}
+
+// step 路径中的一段, 字段名或者[]中的下标/key
+type step struct {
+	field string
+	index string
+	isKey bool
+}
+
+func parsePath(path string) ([]step, error) {
+	var steps []step
+	for len(path) > 0 {
+		switch path[0] {
+		case '.':
+			path = path[1:]
+		case '[':
+			var raw string
+			if len(path) > 1 && path[1] == '"' {
+				quoted, err := strconv.QuotedPrefix(path[1:])
+				if err != nil {
+					return nil, ErrInvalidPath
+				}
+				raw = quoted
+			} else {
+				end := strings.IndexByte(path, ']')
+				if end < 0 {
+					return nil, ErrInvalidPath
+				}
+				raw = path[1:end]
+			}
+			if len(path) < len(raw)+2 || path[len(raw)+1] != ']' {
+				return nil, ErrInvalidPath
+			}
+			steps = append(steps, step{index: raw, isKey: true})
+			path = path[len(raw)+2:]
+			continue
+		}
+		end := strings.IndexAny(path, ".[")
+		if end < 0 {
+			end = len(path)
+		}
+		if end == 0 {
+			return nil, ErrInvalidPath
+		}
+		steps = append(steps, step{field: path[:end]})
+		path = path[end:]
+	}
+	return steps, nil
+}
+
+func apply(v reflect.Value, steps []step, change Change) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if len(steps) == 0 {
+			return set(v, change.To)
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return apply(exported(v.Elem()), steps, change)
+	case reflect.Interface:
+		if len(steps) == 0 || v.IsNil() {
+			return set(v, change.To)
+		}
+		elem := addressable(v.Elem())
+		if err := apply(elem, steps, change); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+	if len(steps) == 0 {
+		return set(v, change.To)
+	}
+
+	s := steps[0]
+	switch v.Kind() {
+	case reflect.Struct:
+		if s.isKey {
+			return ErrInvalidPath
+		}
+		field, ok := v.Type().FieldByName(s.field)
+		if !ok || len(field.Index) != 1 {
+			return fmt.Errorf("%w: no field %s in %s", ErrInvalidPath, s.field, v.Type())
+		}
+		return apply(exported(v.Field(field.Index[0])), steps[1:], change)
+	case reflect.Slice, reflect.Array:
+		if !s.isKey {
+			return ErrInvalidPath
+		}
+		i, err := strconv.Atoi(s.index)
+		if err != nil {
+			return ErrInvalidPath
+		}
+		if i < 0 || i >= v.Len() {
+			return ErrIndexOutRange
+		}
+		return apply(exported(v.Index(i)), steps[1:], change)
+	case reflect.Map:
+		if !s.isKey {
+			return ErrInvalidPath
+		}
+		key, err := parseKey(s.index, v.Type().Key())
+		if err != nil {
+			return err
+		}
+		if len(steps) == 1 && change.Type == Removed {
+			if !v.IsNil() {
+				v.SetMapIndex(key, reflect.Value{})
+			}
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if old := v.MapIndex(key); old.IsValid() {
+			elem.Set(old)
+		}
+		if err = apply(elem, steps[1:], change); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+		return nil
+	default:
+		return ErrInvalidPath
+	}
+}
+
+// set 将value赋值给v, value为nil时设置为零值, 类型不同但可以转换时(如JSON解码得到的float64)会进行转换
+func set(v reflect.Value, value interface{}) error {
+	if value == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	rv := reflect.ValueOf(Clone(value))
+	switch {
+	case rv.Type().AssignableTo(v.Type()):
+		v.Set(rv)
+	case rv.Type().ConvertibleTo(v.Type()):
+		v.Set(rv.Convert(v.Type()))
+	default:
+		return fmt.Errorf("cannot assign %s to %s", rv.Type(), v.Type())
+	}
+	return nil
+}
+
+func parseKey(raw string, typ reflect.Type) (reflect.Value, error) {
+	key := reflect.New(typ).Elem()
+	switch typ.Kind() {
+	case reflect.String:
+		s, err := strconv.Unquote(raw)
+		if err != nil {
+			return key, ErrInvalidPath
+		}
+		key.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return key, ErrInvalidPath
+		}
+		key.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return key, ErrInvalidPath
+		}
+		key.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return key, ErrInvalidPath
+		}
+		key.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return key, ErrInvalidPath
+		}
+		key.SetBool(b)
+	default:
+		return key, ErrUnsupportedKey
+	}
+	return key, nil
+}
@@ -0,0 +1,305 @@
+package clones
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrInvalidArgument = errors.New("dst must be a non-nil pointer to struct and src must be a struct or pointer to struct")
+
+// CopyReport CopyInto的结果, 记录没有匹配上的字段
+type CopyReport struct {
+	UnmatchedDst []string // dst中没有被赋值的字段
+	UnmatchedSrc []string // src中没有被使用的字段
+}
+
+// CopyInto 将src中的字段按名称拷贝到类型不同的dst中, 名称可以通过`copy:"name"`标签指定, `copy:"-"`表示忽略该字段,
+// 名称精确匹配失败时会忽略大小写再次匹配, 匿名结构体中的字段会被展开, 支持以下转换:
+// 不同宽度的整数和浮点数、指针与值、time.Time与RFC3339格式的字符串、元素类型不同的slice和map、嵌套的结构体
+func CopyInto(dst, src interface{}) (*CopyReport, error) {
+	dv := reflect.ValueOf(dst)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() || dv.Elem().Kind() != reflect.Struct {
+		return nil, ErrInvalidArgument
+	}
+	sv := reflect.ValueOf(src)
+	for sv.Kind() == reflect.Ptr && !sv.IsNil() {
+		sv = sv.Elem()
+	}
+	if sv.Kind() != reflect.Struct {
+		return nil, ErrInvalidArgument
+	}
+	sv = addressable(sv)
+
+	report := &CopyReport{}
+	if err := copyStruct(dv.Elem(), sv, "", report); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// fieldMapping dst与src两个结构体类型之间的字段对应关系, 按类型对缓存
+type fieldMapping struct {
+	pairs        [][2][]int // dst字段的索引路径和src字段的索引路径
+	unmatchedDst []string
+	unmatchedSrc []string
+}
+
+type mappingKey struct {
+	dst, src reflect.Type
+}
+
+var mappings sync.Map // mappingKey -> *fieldMapping
+
+type namedField struct {
+	name  string
+	index []int
+}
+
+// exportedFields 返回结构体中所有可导出的字段, 匿名结构体中的字段会被展开
+func exportedFields(typ reflect.Type, prefix []int) []namedField {
+	var fields []namedField
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		index := append(append([]int{}, prefix...), i)
+		ft := field.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if field.Anonymous && ft.Kind() == reflect.Struct && field.Tag.Get("copy") == "" {
+			fields = append(fields, exportedFields(ft, index)...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Tag.Get("copy")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, namedField{name: name, index: index})
+	}
+	return fields
+}
+
+func mappingOf(dst, src reflect.Type) *fieldMapping {
+	key := mappingKey{dst: dst, src: src}
+	if m, ok := mappings.Load(key); ok {
+		return m.(*fieldMapping)
+	}
+
+	var (
+		m         = &fieldMapping{}
+		srcFields = exportedFields(src, nil)
+		used      = make([]bool, len(srcFields))
+	)
+	find := func(name string, match func(a, b string) bool) int {
+		for i, f := range srcFields {
+			if !used[i] && match(f.name, name) {
+				return i
+			}
+		}
+		return -1
+	}
+	for _, f := range exportedFields(dst, nil) {
+		i := find(f.name, func(a, b string) bool { return a == b })
+		if i < 0 {
+			i = find(f.name, strings.EqualFold)
+		}
+		if i < 0 {
+			m.unmatchedDst = append(m.unmatchedDst, f.name)
+			continue
+		}
+		used[i] = true
+		m.pairs = append(m.pairs, [2][]int{f.index, srcFields[i].index})
+	}
+	for i, f := range srcFields {
+		if !used[i] {
+			m.unmatchedSrc = append(m.unmatchedSrc, f.name)
+		}
+	}
+	mappings.Store(key, m)
+	return m
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func copyStruct(dst, src reflect.Value, prefix string, report *CopyReport) error {
+	m := mappingOf(dst.Type(), src.Type())
+	for _, name := range m.unmatchedDst {
+		report.UnmatchedDst = append(report.UnmatchedDst, joinPath(prefix, name))
+	}
+	for _, name := range m.unmatchedSrc {
+		report.UnmatchedSrc = append(report.UnmatchedSrc, joinPath(prefix, name))
+	}
+
+	for _, pair := range m.pairs {
+		sf, ok := fieldByIndex(src, pair[1], false)
+		if !ok {
+			continue
+		}
+		df, _ := fieldByIndex(dst, pair[0], true)
+		name := joinPath(prefix, dst.Type().FieldByIndex(pair[0]).Name)
+		if err := convert(df, sf, name, report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldByIndex 按索引路径获取字段, 路径中的nil指针在alloc为true时会被创建, 否则返回false
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = exported(v.Field(x))
+	}
+	return v, true
+}
+
+func convert(dst, src reflect.Value, name string, report *CopyReport) error {
+	dt, st := dst.Type(), src.Type()
+
+	switch {
+	case src.Kind() == reflect.Ptr && dst.Kind() != reflect.Ptr:
+		if src.IsNil() {
+			return nil
+		}
+		return convert(dst, src.Elem(), name, report)
+	case dst.Kind() == reflect.Ptr && src.Kind() != reflect.Ptr:
+		v := reflect.New(dt.Elem())
+		if err := convert(v.Elem(), src, name, report); err != nil {
+			return err
+		}
+		dst.Set(v)
+		return nil
+	case src.Kind() == reflect.Ptr && dst.Kind() == reflect.Ptr && st != dt:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dt))
+			return nil
+		}
+		v := reflect.New(dt.Elem())
+		if err := convert(v.Elem(), src.Elem(), name, report); err != nil {
+			return err
+		}
+		dst.Set(v)
+		return nil
+	case st == dt:
+		newCloner().copy(dst, src)
+		return nil
+	case st == timeType && dt.Kind() == reflect.String:
+		dst.SetString(src.Interface().(time.Time).Format(time.RFC3339Nano))
+		return nil
+	case st.Kind() == reflect.String && dt == timeType:
+		if src.String() == "" {
+			dst.Set(reflect.Zero(dt))
+			return nil
+		}
+		t, err := time.Parse(time.RFC3339Nano, src.String())
+		if err != nil {
+			return fmt.Errorf("field %s: %w", name, err)
+		}
+		dst.Set(reflect.ValueOf(t))
+		return nil
+	case isNumber(st.Kind()) && isNumber(dt.Kind()):
+		v := src.Convert(dt)
+		if !representable(src, v) {
+			return fmt.Errorf("field %s: value %v cannot be represented by %s", name, src.Interface(), dt)
+		}
+		dst.Set(v)
+		return nil
+	case st.Kind() == reflect.Struct && dt.Kind() == reflect.Struct && st != timeType && dt != timeType:
+		return copyStruct(dst, src, name, report)
+	case st.Kind() == reflect.Slice && dt.Kind() == reflect.Slice:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dt))
+			return nil
+		}
+		if st.ConvertibleTo(dt) && st.Elem() == dt.Elem() {
+			break
+		}
+		v := reflect.MakeSlice(dt, src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			if err := convert(v.Index(i), src.Index(i), fmt.Sprintf("%s[%d]", name, i), report); err != nil {
+				return err
+			}
+		}
+		dst.Set(v)
+		return nil
+	case st.Kind() == reflect.Map && dt.Kind() == reflect.Map:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dt))
+			return nil
+		}
+		v := reflect.MakeMapWithSize(dt, src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			key := reflect.New(dt.Key()).Elem()
+			if err := convert(key, addressable(iter.Key()), name, report); err != nil {
+				return err
+			}
+			elem := reflect.New(dt.Elem()).Elem()
+			if err := convert(elem, addressable(iter.Value()), fmt.Sprintf("%s[%v]", name, iter.Key().Interface()), report); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, elem)
+		}
+		dst.Set(v)
+		return nil
+	}
+
+	// 其余可以直接转换的类型, 如底层类型相同的自定义类型、string与[]byte, 但不允许整数转换为string
+	if st.ConvertibleTo(dt) && !(isNumber(st.Kind()) && dt.Kind() == reflect.String) {
+		newCloner().copy(dst, addressable(src.Convert(dt)))
+		return nil
+	}
+	return fmt.Errorf("field %s: cannot convert %s to %s", name, st, dt)
+}
+
+// representable 判断数值src转换为v后是否没有损失, 除了往返转换后相等外, 还要求符号不变
+func representable(src, v reflect.Value) bool {
+	if !v.Convert(src.Type()).Equal(src) {
+		return false
+	}
+	return isNegative(src) == isNegative(v)
+}
+
+func isNegative(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() < 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() < 0
+	default:
+		return false
+	}
+}
+
+func isNumber(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,116 @@
+package clones
+
+import (
+	"reflect"
+	"sync"
+)
+
+// plan 某个类型的拷贝计划, 首次拷贝该类型时生成并缓存, 避免每次拷贝都重复反射字段和解析标签
+type plan struct {
+	typ        reflect.Type
+	kind       reflect.Kind
+	pod        bool                              // 不包含需要深拷贝的内容, 可以直接赋值
+	copier     func(reflect.Value) reflect.Value // 注册的拷贝函数
+	deepCopier bool                              // 是否调用DeepCopier
+	elem       *plan                             // 指针、slice、array、map的元素
+	key        *plan                             // map的key
+	fields     []fieldPlan                       // 结构体中需要拷贝的字段
+}
+
+type fieldPlan struct {
+	index    int
+	exported bool
+	plan     *plan
+}
+
+var (
+	planMu sync.Mutex
+	plans  sync.Map // reflect.Type -> *plan
+)
+
+// resetPlans 注册拷贝函数后清空缓存, 因为包含该类型的其他类型的计划也会改变
+func resetPlans() {
+	planMu.Lock()
+	plans.Range(func(key, _ interface{}) bool {
+		plans.Delete(key)
+		return true
+	})
+	planMu.Unlock()
+}
+
+func planOf(typ reflect.Type) *plan {
+	if p, ok := plans.Load(typ); ok {
+		return p.(*plan)
+	}
+
+	planMu.Lock()
+	defer planMu.Unlock()
+	building := make(map[reflect.Type]*plan)
+	p := buildPlan(typ, building)
+	for t, bp := range building {
+		plans.Store(t, bp)
+	}
+	return p
+}
+
+func buildPlan(typ reflect.Type, building map[reflect.Type]*plan) *plan {
+	if p, ok := plans.Load(typ); ok {
+		return p.(*plan)
+	}
+	if p, ok := building[typ]; ok {
+		// 递归类型, 必然经过指针、slice或map, 所以不是pod
+		return p
+	}
+
+	p := &plan{
+		typ:  typ,
+		kind: typ.Kind(),
+	}
+	building[typ] = p
+
+	if fn, ok := copiers.Load(typ); ok {
+		p.copier = fn.(func(reflect.Value) reflect.Value)
+		return p
+	}
+	if typ.Kind() != reflect.Interface && typ.Implements(deepCopierType) &&
+		(typ.Kind() != reflect.Ptr || !typ.Elem().Implements(deepCopierType)) {
+		p.deepCopier = true
+		return p
+	}
+
+	switch typ.Kind() {
+	case reflect.Interface, reflect.Chan:
+	case reflect.Ptr, reflect.Slice:
+		p.elem = buildPlan(typ.Elem(), building)
+	case reflect.Map:
+		p.key = buildPlan(typ.Key(), building)
+		p.elem = buildPlan(typ.Elem(), building)
+	case reflect.Array:
+		p.elem = buildPlan(typ.Elem(), building)
+		p.pod = p.elem.pod
+	case reflect.Struct:
+		if typ == timeType {
+			p.pod = true
+			return p
+		}
+		p.pod = true
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if field.Tag.Get("deepcopy") == "-" {
+				p.pod = false
+				continue
+			}
+			fp := buildPlan(field.Type, building)
+			p.pod = p.pod && fp.pod
+			p.fields = append(p.fields, fieldPlan{
+				index:    i,
+				exported: field.IsExported(),
+				plan:     fp,
+			})
+		}
+	default:
+		// 基础类型、string、func、unsafe.Pointer按值拷贝
+		p.pod = true
+	}
+	return p
+}
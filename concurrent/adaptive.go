@@ -0,0 +1,211 @@
+package concurrent
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pyihe/go-pkg/errors"
+)
+
+var ErrLimitExceeded = errors.New("concurrency limit exceeded")
+
+const (
+	defaultAdaptiveMin      = 1
+	defaultAdaptiveMax      = 1000
+	defaultAdaptiveInitial  = 20
+	defaultLatencyTolerance = 2.0
+	defaultBackoffRatio     = 0.9
+	baselineDecay           = 0.01 // 基准延迟向上追随的速度, 使其可以适应延迟的长期变化
+)
+
+// AdaptiveOption AdaptiveLimiter的选项
+type AdaptiveOption func(*AdaptiveLimiter)
+
+// WithLimitRange 并发上限的调整范围, 默认为[1, 1000]
+func WithLimitRange(min, max int) AdaptiveOption {
+	return func(l *AdaptiveLimiter) {
+		l.minLimit = min
+		l.maxLimit = max
+	}
+}
+
+// WithInitialLimit 初始的并发上限, 默认为20
+func WithInitialLimit(n int) AdaptiveOption {
+	return func(l *AdaptiveLimiter) {
+		l.limit = float64(n)
+	}
+}
+
+// WithLatencyTolerance 延迟超过基准延迟的tolerance倍时减小上限, 默认为2
+func WithLatencyTolerance(tolerance float64) AdaptiveOption {
+	return func(l *AdaptiveLimiter) {
+		l.tolerance = tolerance
+	}
+}
+
+// WithBackoffRatio 减小上限时乘以的比例, 默认为0.9
+func WithBackoffRatio(ratio float64) AdaptiveOption {
+	return func(l *AdaptiveLimiter) {
+		l.backoff = ratio
+	}
+}
+
+// WithOnLimitChange 上限变化时的回调, 在持有锁时调用, 回调中不能调用AdaptiveLimiter的方法
+func WithOnLimitChange(fn func(limit int)) AdaptiveOption {
+	return func(l *AdaptiveLimiter) {
+		l.onChange = fn
+	}
+}
+
+// AdaptiveLimiter 自适应并发限制器(AIMD), 延迟接近基准且上限被充分使用时逐步增大上限,
+// 延迟升高或者请求失败时按比例减小上限
+type AdaptiveLimiter struct {
+	minLimit  int
+	maxLimit  int
+	tolerance float64
+	backoff   float64
+	onChange  func(limit int)
+
+	mu       sync.Mutex
+	limit    float64
+	inflight int
+	baseline float64 // 基准延迟(纳秒)
+	notify   chan struct{}
+}
+
+// NewAdaptiveLimiter 创建自适应并发限制器
+func NewAdaptiveLimiter(opts ...AdaptiveOption) *AdaptiveLimiter {
+	l := &AdaptiveLimiter{
+		minLimit:  defaultAdaptiveMin,
+		maxLimit:  defaultAdaptiveMax,
+		tolerance: defaultLatencyTolerance,
+		backoff:   defaultBackoffRatio,
+		limit:     defaultAdaptiveInitial,
+		notify:    make(chan struct{}),
+	}
+	for _, op := range opts {
+		op(l)
+	}
+	if l.minLimit < 1 {
+		l.minLimit = 1
+	}
+	if l.maxLimit < l.minLimit {
+		l.maxLimit = l.minLimit
+	}
+	l.limit = math.Max(float64(l.minLimit), math.Min(float64(l.maxLimit), l.limit))
+	return l
+}
+
+// Limit 当前的并发上限
+func (l *AdaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// Inflight 当前正在执行的请求数
+func (l *AdaptiveLimiter) Inflight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inflight
+}
+
+// TryAcquire 未达到上限时返回许可, 否则返回ErrLimitExceeded
+func (l *AdaptiveLimiter) TryAcquire() (*Permit, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inflight >= int(l.limit) {
+		return nil, ErrLimitExceeded
+	}
+	return l.grant(), nil
+}
+
+// Acquire 阻塞直到获得许可或者ctx被取消
+func (l *AdaptiveLimiter) Acquire(ctx context.Context) (*Permit, error) {
+	for {
+		l.mu.Lock()
+		if l.inflight < int(l.limit) {
+			p := l.grant()
+			l.mu.Unlock()
+			return p, nil
+		}
+		notify := l.notify
+		l.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// grant 调用时需持有锁
+func (l *AdaptiveLimiter) grant() *Permit {
+	l.inflight++
+	return &Permit{limiter: l, start: time.Now()}
+}
+
+// release 归还许可并根据延迟和结果调整上限, sample为false时不调整
+func (l *AdaptiveLimiter) release(rtt time.Duration, failed, sample bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	inflight := l.inflight
+	l.inflight--
+	// 唤醒所有等待者重新检查
+	close(l.notify)
+	l.notify = make(chan struct{})
+	if !sample {
+		return
+	}
+
+	old := int(l.limit)
+	latency := float64(rtt)
+	switch {
+	case l.baseline == 0 || latency < l.baseline:
+		l.baseline = latency
+	default:
+		l.baseline += (latency - l.baseline) * baselineDecay
+	}
+	switch {
+	case failed || latency > l.baseline*l.tolerance:
+		l.limit = math.Max(float64(l.minLimit), l.limit*l.backoff)
+	case inflight*2 >= old:
+		// 上限被充分使用时才增大, 避免空闲时无限增长
+		l.limit = math.Min(float64(l.maxLimit), l.limit+1)
+	}
+	if current := int(l.limit); current != old && l.onChange != nil {
+		l.onChange(current)
+	}
+}
+
+// Permit 并发许可, 请求结束后必须调用Success、Failure、Ignore中的一个
+type Permit struct {
+	limiter *AdaptiveLimiter
+	start   time.Time
+	once    sync.Once
+}
+
+// Success 请求成功, 以请求的耗时调整上限
+func (p *Permit) Success() {
+	p.once.Do(func() {
+		p.limiter.release(time.Since(p.start), false, true)
+	})
+}
+
+// Failure 请求失败(如超时、过载), 减小上限
+func (p *Permit) Failure() {
+	p.once.Do(func() {
+		p.limiter.release(time.Since(p.start), true, true)
+	})
+}
+
+// Ignore 归还许可但不调整上限, 用于与负载无关的失败(如参数错误)
+func (p *Permit) Ignore() {
+	p.once.Do(func() {
+		p.limiter.release(0, false, false)
+	})
+}
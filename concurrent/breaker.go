@@ -0,0 +1,285 @@
+package concurrent
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pyihe/go-pkg/errors"
+)
+
+var (
+	ErrBreakerOpen   = errors.New("circuit breaker is open")
+	ErrTooManyProbes = errors.New("too many probes in half-open state")
+)
+
+const (
+	defaultConsecutiveFailures = 5
+	defaultCoolDown            = 30 * time.Second
+	windowBuckets              = 10
+)
+
+// BreakerState 熔断器状态
+type BreakerState int32
+
+const (
+	StateClosed   BreakerState = iota // 正常放行
+	StateOpen                         // 熔断, 直接拒绝
+	StateHalfOpen                     // 冷却结束, 放行有限的探测请求
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerOption Breaker的选项
+type BreakerOption func(*Breaker)
+
+// WithConsecutiveFailures 连续失败n次后熔断, 默认为5, n为0时不按连续失败次数熔断
+func WithConsecutiveFailures(n int) BreakerOption {
+	return func(b *Breaker) {
+		b.maxFailures = n
+	}
+}
+
+// WithFailureRatio 最近window时间内请求数不少于minRequests且失败比例达到ratio时熔断
+func WithFailureRatio(ratio float64, minRequests int, window time.Duration) BreakerOption {
+	return func(b *Breaker) {
+		b.ratio = ratio
+		b.minRequests = minRequests
+		b.window = window
+	}
+}
+
+// WithCoolDown 熔断后经过d进入半开状态, 默认为30秒
+func WithCoolDown(d time.Duration) BreakerOption {
+	return func(b *Breaker) {
+		b.coolDown = d
+	}
+}
+
+// WithHalfOpenProbes 半开状态下最多同时放行n个探测请求, n个探测都成功后恢复正常, 默认为1
+func WithHalfOpenProbes(n int) BreakerOption {
+	return func(b *Breaker) {
+		b.maxProbes = n
+	}
+}
+
+// WithIsFailure 判断fn返回的错误是否计为失败, 默认所有非nil的错误都计为失败
+func WithIsFailure(fn func(err error) bool) BreakerOption {
+	return func(b *Breaker) {
+		b.isFailure = fn
+	}
+}
+
+// WithOnStateChange 状态变化时的回调, 可用于记录日志
+func WithOnStateChange(fn func(from, to BreakerState)) BreakerOption {
+	return func(b *Breaker) {
+		b.onStateChange = fn
+	}
+}
+
+type bucket struct {
+	start    time.Time
+	requests int
+	failures int
+}
+
+type transition struct {
+	from, to BreakerState
+}
+
+// Breaker 熔断器
+type Breaker struct {
+	maxFailures   int
+	ratio         float64
+	minRequests   int
+	window        time.Duration
+	coolDown      time.Duration
+	maxProbes     int
+	isFailure     func(err error) bool
+	onStateChange func(from, to BreakerState)
+
+	mu          sync.Mutex
+	state       BreakerState
+	generation  uint64    // 每次状态变化加1, 用于忽略状态变化前发起的请求的结果
+	openedAt    time.Time // 进入熔断状态的时间
+	consecutive int       // 连续失败次数
+	probes      int       // 半开状态下正在进行的探测数
+	successes   int       // 半开状态下成功的探测数
+	buckets     [windowBuckets]bucket
+	changes     []transition
+}
+
+// NewBreaker 创建熔断器
+func NewBreaker(opts ...BreakerOption) *Breaker {
+	b := &Breaker{
+		maxFailures: defaultConsecutiveFailures,
+		coolDown:    defaultCoolDown,
+		maxProbes:   1,
+	}
+	for _, op := range opts {
+		op(b)
+	}
+	if b.maxProbes < 1 {
+		b.maxProbes = 1
+	}
+	return b
+}
+
+// State 当前状态
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	state := b.currentState(time.Now())
+	b.unlock()
+	return state
+}
+
+// Execute 熔断器允许时执行fn并记录结果, 否则返回ErrBreakerOpen或ErrTooManyProbes,
+// fn panic时计为失败并继续panic
+func (b *Breaker) Execute(fn func() error) error {
+	generation, err := b.before()
+	if err != nil {
+		return err
+	}
+	success := false
+	defer func() {
+		b.after(generation, success)
+	}()
+
+	err = fn()
+	success = err == nil || (b.isFailure != nil && !b.isFailure(err))
+	return err
+}
+
+func (b *Breaker) before() (uint64, error) {
+	b.mu.Lock()
+	defer b.unlock()
+
+	switch b.currentState(time.Now()) {
+	case StateOpen:
+		return 0, ErrBreakerOpen
+	case StateHalfOpen:
+		if b.probes >= b.maxProbes {
+			return 0, ErrTooManyProbes
+		}
+		b.probes++
+	}
+	return b.generation, nil
+}
+
+func (b *Breaker) after(generation uint64, success bool) {
+	b.mu.Lock()
+	defer b.unlock()
+
+	now := time.Now()
+	state := b.currentState(now)
+	if generation != b.generation {
+		return
+	}
+	switch state {
+	case StateClosed:
+		b.record(now, success)
+		if success {
+			b.consecutive = 0
+			return
+		}
+		b.consecutive++
+		if b.shouldTrip(now) {
+			b.setState(StateOpen, now)
+		}
+	case StateHalfOpen:
+		b.probes--
+		if !success {
+			b.setState(StateOpen, now)
+			return
+		}
+		b.successes++
+		if b.successes >= b.maxProbes {
+			b.setState(StateClosed, now)
+		}
+	}
+}
+
+// currentState 冷却时间结束后由熔断转为半开
+func (b *Breaker) currentState(now time.Time) BreakerState {
+	if b.state == StateOpen && now.Sub(b.openedAt) >= b.coolDown {
+		b.setState(StateHalfOpen, now)
+	}
+	return b.state
+}
+
+func (b *Breaker) setState(state BreakerState, now time.Time) {
+	if b.state == state {
+		return
+	}
+	b.changes = append(b.changes, transition{from: b.state, to: state})
+	b.state = state
+	b.generation++
+	b.consecutive = 0
+	b.probes = 0
+	b.successes = 0
+	b.buckets = [windowBuckets]bucket{}
+	if state == StateOpen {
+		b.openedAt = now
+	}
+}
+
+// record 将结果记录到滑动窗口
+func (b *Breaker) record(now time.Time, success bool) {
+	if b.window <= 0 {
+		return
+	}
+	span := b.window / windowBuckets
+	if span <= 0 {
+		span = 1
+	}
+	start := now.Truncate(span)
+	bk := &b.buckets[(start.UnixNano()/int64(span))%windowBuckets]
+	if !bk.start.Equal(start) {
+		*bk = bucket{start: start}
+	}
+	bk.requests++
+	if !success {
+		bk.failures++
+	}
+}
+
+func (b *Breaker) shouldTrip(now time.Time) bool {
+	if b.maxFailures > 0 && b.consecutive >= b.maxFailures {
+		return true
+	}
+	if b.window <= 0 || b.ratio <= 0 {
+		return false
+	}
+	var requests, failures int
+	for _, bk := range b.buckets {
+		if now.Sub(bk.start) < b.window {
+			requests += bk.requests
+			failures += bk.failures
+		}
+	}
+	return requests > 0 && requests >= b.minRequests && float64(failures)/float64(requests) >= b.ratio
+}
+
+// unlock 解锁后再执行状态变化的回调, 回调中可以安全地调用Breaker的方法
+func (b *Breaker) unlock() {
+	changes := b.changes
+	b.changes = nil
+	b.mu.Unlock()
+
+	if b.onStateChange == nil {
+		return
+	}
+	for _, c := range changes {
+		b.onStateChange(c.from, c.to)
+	}
+}
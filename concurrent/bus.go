@@ -0,0 +1,312 @@
+package concurrent
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pyihe/go-pkg/errors"
+)
+
+var ErrBusClosed = errors.New("bus closed")
+
+const defaultSubscriptionBuffer = 64
+
+// OverflowPolicy 订阅者的缓冲区已满时的处理方式
+type OverflowPolicy int
+
+const (
+	OverflowDropOldest OverflowPolicy = iota // 丢弃缓冲区中最旧的事件
+	OverflowDropNewest                       // 丢弃新的事件
+	OverflowBlock                            // 阻塞发布者直到缓冲区有空位
+)
+
+// Event 发布的事件
+type Event struct {
+	Topic   string
+	Payload interface{}
+}
+
+// SubscribeOption 订阅选项
+type SubscribeOption func(*Subscription)
+
+// WithBufferSize 订阅者的缓冲区大小, 默认为64
+func WithBufferSize(n int) SubscribeOption {
+	return func(s *Subscription) {
+		s.size = n
+	}
+}
+
+// WithOverflowPolicy 缓冲区已满时的处理方式, 默认为OverflowDropOldest
+func WithOverflowPolicy(policy OverflowPolicy) SubscribeOption {
+	return func(s *Subscription) {
+		s.policy = policy
+	}
+}
+
+// WithHandler 使用回调接收事件, 回调在订阅者自己的goroutine中按顺序执行, 此时C返回nil;
+// 回调中可以调用Bus.Close, 但此时Close不会等待回调方式的订阅处理完剩余的事件
+func WithHandler(handler func(Event)) SubscribeOption {
+	return func(s *Subscription) {
+		s.handler = handler
+	}
+}
+
+// Subscription 订阅
+type Subscription struct {
+	bus     *Bus
+	pattern []string
+	size    int
+	policy  OverflowPolicy
+	handler func(Event)
+
+	mu      sync.Mutex
+	closed  bool
+	ch      chan Event
+	done    chan struct{}
+	drained chan struct{} // 回调方式下处理完剩余事件后关闭
+	once    sync.Once
+	dropped uint64
+}
+
+// Pattern 订阅的主题
+func (s *Subscription) Pattern() string {
+	return strings.Join(s.pattern, ".")
+}
+
+// C 接收事件的channel, 取消订阅或者Bus关闭后, 读完剩余的事件即关闭
+func (s *Subscription) C() <-chan Event {
+	if s.handler != nil {
+		return nil
+	}
+	return s.ch
+}
+
+// Dropped 因缓冲区已满而丢弃的事件数量
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Unsubscribe 取消订阅, 已经在缓冲区中的事件仍会被投递
+func (s *Subscription) Unsubscribe() {
+	// 先关闭订阅以唤醒阻塞在该订阅上的发布者
+	s.close()
+	s.bus.remove(s)
+}
+
+func (s *Subscription) close() {
+	s.once.Do(func() {
+		close(s.done)
+		s.mu.Lock()
+		s.closed = true
+		close(s.ch)
+		s.mu.Unlock()
+	})
+}
+
+func (s *Subscription) deliver(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.ch <- e:
+		return
+	default:
+	}
+
+	switch s.policy {
+	case OverflowDropNewest:
+		atomic.AddUint64(&s.dropped, 1)
+	case OverflowBlock:
+		select {
+		case s.ch <- e:
+		case <-s.done:
+		case <-s.bus.done:
+		}
+	default:
+		// 接收者可能同时在读, 循环直到放入
+		for {
+			select {
+			case <-s.ch:
+				atomic.AddUint64(&s.dropped, 1)
+			default:
+			}
+			select {
+			case s.ch <- e:
+				return
+			default:
+			}
+		}
+	}
+}
+
+func (s *Subscription) run() {
+	defer close(s.drained)
+	for e := range s.ch {
+		s.handler(e)
+	}
+}
+
+var runFunc = runtime.FuncForPC(reflect.ValueOf((*Subscription).run).Pointer()).Name()
+
+// inHandler 判断当前goroutine是否正在执行订阅的回调
+func inHandler() bool {
+	pcs := make([]uintptr, 32)
+	for {
+		n := runtime.Callers(2, pcs)
+		if n < len(pcs) {
+			pcs = pcs[:n]
+			break
+		}
+		pcs = make([]uintptr, 2*len(pcs))
+	}
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if frame.Function == runFunc {
+			return true
+		}
+		if !more {
+			return false
+		}
+	}
+}
+
+// match 判断topic是否匹配pattern, "*"匹配一段, "**"匹配零段或多段, 段之间以"."分隔
+func match(pattern, topic []string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case "**":
+			for i := 0; i <= len(topic); i++ {
+				if match(pattern[1:], topic[i:]) {
+					return true
+				}
+			}
+			return false
+		case "*":
+			if len(topic) == 0 {
+				return false
+			}
+		default:
+			if len(topic) == 0 || pattern[0] != topic[0] {
+				return false
+			}
+		}
+		pattern, topic = pattern[1:], topic[1:]
+	}
+	return len(topic) == 0
+}
+
+// Bus 进程内的发布/订阅总线
+type Bus struct {
+	mu     sync.RWMutex
+	closed bool
+	subs   map[*Subscription]struct{}
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewBus 创建Bus
+func NewBus() *Bus {
+	return &Bus{
+		subs: make(map[*Subscription]struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// Subscribe 订阅匹配pattern的主题, 如"room.*.join"、"room.**"
+func (b *Bus) Subscribe(pattern string, opts ...SubscribeOption) (*Subscription, error) {
+	s := &Subscription{
+		bus:     b,
+		pattern: strings.Split(pattern, "."),
+		size:    defaultSubscriptionBuffer,
+		done:    make(chan struct{}),
+	}
+	for _, op := range opts {
+		op(s)
+	}
+	if s.size < 1 {
+		s.size = 1
+	}
+	s.ch = make(chan Event, s.size)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrBusClosed
+	}
+	b.subs[s] = struct{}{}
+	if s.handler != nil {
+		s.drained = make(chan struct{})
+		go s.run()
+	}
+	return s, nil
+}
+
+func (b *Bus) remove(s *Subscription) {
+	b.mu.Lock()
+	delete(b.subs, s)
+	b.mu.Unlock()
+}
+
+// Publish 向所有匹配topic的订阅者发布事件
+func (b *Bus) Publish(topic string, payload interface{}) error {
+	var (
+		subs     []*Subscription
+		segments = strings.Split(topic, ".")
+	)
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return ErrBusClosed
+	}
+	for s := range b.subs {
+		if match(s.pattern, segments) {
+			subs = append(subs, s)
+		}
+	}
+	b.mu.RUnlock()
+
+	// 投递时不持有锁, 阻塞的投递不会影响其他的发布、订阅和取消订阅
+	e := Event{Topic: topic, Payload: payload}
+	for _, s := range subs {
+		s.deliver(e)
+	}
+	return nil
+}
+
+// Close 关闭Bus, 之后Publish返回ErrBusClosed; 所有订阅的channel在读完剩余事件后关闭,
+// 回调方式的订阅会在Close返回前处理完剩余的事件, 在回调中调用时不等待, 否则会等待自己而死锁
+func (b *Bus) Close() {
+	// 唤醒阻塞的发布者
+	b.closeOnce.Do(func() {
+		close(b.done)
+	})
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	subs := b.subs
+	b.subs = make(map[*Subscription]struct{})
+	b.mu.Unlock()
+
+	for s := range subs {
+		s.close()
+	}
+	if inHandler() {
+		return
+	}
+	for s := range subs {
+		if s.drained != nil {
+			<-s.drained
+		}
+	}
+}
@@ -1,44 +1,164 @@
 package concurrent
 
 import (
+	"container/list"
+	"context"
 	"sync"
 
+	"github.com/pyihe/go-pkg/errors"
 	"github.com/pyihe/go-pkg/maths"
 )
 
+var (
+	ErrInvalidWeight = errors.New("weight must be positive")
+	ErrWeightTooBig  = errors.New("weight exceeds limiter size")
+)
+
+type waiter struct {
+	weight int
+	ready  chan struct{}
+}
+
+// Limiter 带权重的并发限制器, 同时持有的许可总数不超过size, 等待者按照先来先得的顺序获得许可
 type Limiter struct {
-	queue  chan struct{}
-	waiter *sync.WaitGroup
+	mu      sync.Mutex
+	size    int
+	cur     int
+	waiters list.List
+	waiter  *sync.WaitGroup
 }
 
 func NewLimiter(size int) *Limiter {
 	size = maths.MaxInt(0, size)
 	return &Limiter{
-		queue:  make(chan struct{}, size),
+		size:   size,
 		waiter: &sync.WaitGroup{},
 	}
 }
 
+// Acquire 获取weight个许可, 许可不足时阻塞直到获得许可或者ctx被取消
+func (lim *Limiter) Acquire(ctx context.Context, weight int) error {
+	if weight <= 0 {
+		return ErrInvalidWeight
+	}
+	lim.mu.Lock()
+	if weight > lim.size {
+		lim.mu.Unlock()
+		return ErrWeightTooBig
+	}
+	if lim.size-lim.cur >= weight && lim.waiters.Len() == 0 {
+		lim.cur += weight
+		lim.mu.Unlock()
+		return nil
+	}
+
+	w := waiter{weight: weight, ready: make(chan struct{})}
+	elem := lim.waiters.PushBack(w)
+	lim.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		lim.mu.Lock()
+		defer lim.mu.Unlock()
+		select {
+		case <-w.ready:
+			// 取消的同时已经获得了许可, 归还
+			lim.cur -= weight
+		default:
+			lim.waiters.Remove(elem)
+		}
+		// 等待者被移除或许可被归还后, 后面的等待者可能已经可以获得许可
+		lim.notifyWaiters()
+		return ctx.Err()
+	}
+}
+
+// TryAcquire 尝试获取weight个许可, 许可不足时立即返回false
+func (lim *Limiter) TryAcquire(weight int) bool {
+	if weight <= 0 {
+		return false
+	}
+	lim.mu.Lock()
+	ok := lim.size-lim.cur >= weight && lim.waiters.Len() == 0
+	if ok {
+		lim.cur += weight
+	}
+	lim.mu.Unlock()
+	return ok
+}
+
+// Release 归还weight个许可
+func (lim *Limiter) Release(weight int) {
+	lim.mu.Lock()
+	lim.cur -= weight
+	if lim.cur < 0 {
+		lim.mu.Unlock()
+		panic("concurrent: released more than held")
+	}
+	lim.notifyWaiters()
+	lim.mu.Unlock()
+}
+
+func (lim *Limiter) notifyWaiters() {
+	for {
+		next := lim.waiters.Front()
+		if next == nil {
+			break
+		}
+		w := next.Value.(waiter)
+		if lim.size-lim.cur < w.weight {
+			// 保证先来先得, 队首的等待者不满足时后面的也不唤醒
+			break
+		}
+		lim.cur += w.weight
+		lim.waiters.Remove(next)
+		close(w.ready)
+	}
+}
+
+// Size 许可总数
+func (lim *Limiter) Size() int {
+	return lim.size
+}
+
+// Current 已经被持有的许可数
+func (lim *Limiter) Current() int {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	return lim.cur
+}
+
+// Available 当前可用的许可数
+func (lim *Limiter) Available() int {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	return lim.size - lim.cur
+}
+
+// Add delta为正数时获取delta个许可(阻塞)并增加等待计数, 为负数时归还-delta个许可并减少等待计数,
+// 新代码建议使用Acquire/Release
 func (lim *Limiter) Add(delta int) {
 	switch {
 	case delta > 0:
-		for i := 0; i < delta; i++ {
-			lim.queue <- struct{}{}
-		}
-	default:
-		for i := 0; i > delta; i-- {
-			<-lim.queue
+		if err := lim.Acquire(context.Background(), delta); err != nil {
+			panic(err)
 		}
+	case delta < 0:
+		lim.Release(-delta)
 	}
 
 	lim.waiter.Add(delta)
 }
 
+// Done 归还一个许可并减少等待计数, 与Add(1)配合使用
 func (lim *Limiter) Done() {
-	<-lim.queue
+	lim.Release(1)
 	lim.waiter.Done()
 }
 
+// Wait 等待所有通过Add增加的计数归零
 func (lim *Limiter) Wait() {
 	lim.waiter.Wait()
 }
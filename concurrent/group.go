@@ -0,0 +1,158 @@
+package concurrent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// GroupError 开启WithCollectErrors时Wait返回的错误, 按完成顺序记录了所有任务返回的错误
+type GroupError struct {
+	Errors []error
+}
+
+func (e *GroupError) Error() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%d task(s) failed", len(e.Errors)))
+	for i, err := range e.Errors {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
+		}
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+// Unwrap 使errors.Is/errors.As可以匹配其中任意一个错误
+func (e *GroupError) Unwrap() []error {
+	return e.Errors
+}
+
+// GroupOption Group的选项
+type GroupOption func(*Group)
+
+// WithCollectErrors 收集所有任务返回的错误, Wait返回*GroupError, 默认只返回第一个错误
+func WithCollectErrors() GroupOption {
+	return func(g *Group) {
+		g.collect = true
+	}
+}
+
+// Group 一组共享同一个context的任务, 任意任务返回错误(或panic)时取消该context;
+// 零值可以直接使用, 此时任务收到的是context.Background(), 失败时不会取消其他任务
+type Group struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	sem     chan struct{}
+	collect bool
+
+	mu   sync.Mutex
+	errs []error
+}
+
+// NewGroup 创建Group, 返回的context会在第一个任务失败或者Wait返回时被取消
+func NewGroup(ctx context.Context, opts ...GroupOption) (*Group, context.Context) {
+	g := &Group{}
+	for _, op := range opts {
+		op(g)
+	}
+	g.ctx, g.cancel = context.WithCancel(ctx)
+	return g, g.ctx
+}
+
+// SetLimit 设置同时运行的任务数量上限, n小于0时不限制, 有任务运行时修改会panic
+func (g *Group) SetLimit(n int) {
+	if n < 0 {
+		g.sem = nil
+		return
+	}
+	if len(g.sem) != 0 {
+		panic(fmt.Errorf("concurrent: modify limit while %d tasks are still active", len(g.sem)))
+	}
+	g.sem = make(chan struct{}, n)
+}
+
+// Go 启动任务, 达到上限时阻塞直到有任务结束
+func (g *Group) Go(fn func(ctx context.Context) error) {
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+	g.start(fn)
+}
+
+// TryGo 未达到上限时启动任务并返回true, 否则返回false
+func (g *Group) TryGo(fn func(ctx context.Context) error) bool {
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		default:
+			return false
+		}
+	}
+	g.start(fn)
+	return true
+}
+
+func (g *Group) start(fn func(ctx context.Context) error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.done()
+		if err := g.run(fn); err != nil {
+			g.fail(err)
+		}
+	}()
+}
+
+func (g *Group) run(fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = newPanicError(r)
+		}
+	}()
+	ctx := g.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return fn(ctx)
+}
+
+func (g *Group) fail(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.errs) == 0 && g.cancel != nil {
+		g.cancel()
+	}
+	if g.collect || len(g.errs) == 0 {
+		g.errs = append(g.errs, err)
+	}
+}
+
+func (g *Group) done() {
+	if g.sem != nil {
+		<-g.sem
+	}
+	g.wg.Done()
+}
+
+// Wait 等待所有任务结束, 返回第一个错误, 开启WithCollectErrors时返回包含所有错误的*GroupError
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	if g.cancel != nil {
+		g.cancel()
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	switch {
+	case len(g.errs) == 0:
+		return nil
+	case g.collect:
+		return &GroupError{Errors: append([]error(nil), g.errs...)}
+	default:
+		return g.errs[0]
+	}
+}
@@ -0,0 +1,309 @@
+package concurrent
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type pipelineKey struct{}
+
+// WithPipeline 创建一组阶段共享的context, 任意阶段失败时以该错误取消context, 上下游的阶段随之停止,
+// 可以通过context.Cause获取第一个错误; 不使用WithPipeline时阶段失败只会停止自身, 调用者需要自己取消上游
+func WithPipeline(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	ctx = context.WithValue(ctx, pipelineKey{}, cancel)
+	return ctx, func() {
+		cancel(context.Canceled)
+	}
+}
+
+// stage 可能失败的阶段, 记录第一个错误并取消阶段内部的context, 在WithPipeline中时同时取消整个pipeline
+type stage struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	abort  context.CancelCauseFunc
+	errc   chan error
+	once   sync.Once
+}
+
+func newStage(ctx context.Context) *stage {
+	s := &stage{errc: make(chan error, 1)}
+	s.abort, _ = ctx.Value(pipelineKey{}).(context.CancelCauseFunc)
+	s.ctx, s.cancel = context.WithCancelCause(ctx)
+	return s
+}
+
+func (s *stage) fail(err error) {
+	s.once.Do(func() {
+		s.errc <- err
+		s.cancel(err)
+		if s.abort != nil {
+			s.abort(err)
+		}
+	})
+}
+
+// finish 阶段结束, 因外部ctx被取消而结束时报告取消的原因
+func (s *stage) finish() {
+	if s.ctx.Err() != nil {
+		s.fail(context.Cause(s.ctx))
+	}
+	s.cancel(nil)
+	close(s.errc)
+}
+
+func recv[T any](ctx context.Context, in <-chan T) (v T, ok bool) {
+	select {
+	case v, ok = <-in:
+		return v, ok
+	case <-ctx.Done():
+		return v, false
+	}
+}
+
+func send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Map 使用workers个goroutine对in中的数据执行fn, 输出的顺序不确定;
+// fn返回错误或者ctx被取消时停止并不再读取in, 错误通过返回的error channel传递, 两个channel都会在阶段结束后关闭
+func Map[T, R any](ctx context.Context, in <-chan T, workers int, fn func(context.Context, T) (R, error)) (<-chan R, <-chan error) {
+	if workers < 1 {
+		workers = 1
+	}
+	var (
+		s   = newStage(ctx)
+		out = make(chan R)
+		wg  sync.WaitGroup
+	)
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for {
+				v, ok := recv(s.ctx, in)
+				if !ok {
+					return
+				}
+				r, err := fn(s.ctx, v)
+				if err != nil {
+					s.fail(err)
+					return
+				}
+				if !send(s.ctx, out, r) {
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		s.finish()
+		close(out)
+	}()
+	return out, s.errc
+}
+
+type orderedResult[R any] struct {
+	val R
+	err error
+}
+
+// OrderedMap 同Map, 但输出的顺序与输入的顺序一致
+func OrderedMap[T, R any](ctx context.Context, in <-chan T, workers int, fn func(context.Context, T) (R, error)) (<-chan R, <-chan error) {
+	if workers < 1 {
+		workers = 1
+	}
+	var (
+		s       = newStage(ctx)
+		out     = make(chan R)
+		sem     = make(chan struct{}, workers)
+		pending = make(chan chan orderedResult[R], workers)
+	)
+	go func() {
+		defer close(pending)
+		for {
+			v, ok := recv(s.ctx, in)
+			if !ok {
+				return
+			}
+			if !send(s.ctx, sem, struct{}{}) {
+				return
+			}
+			rc := make(chan orderedResult[R], 1)
+			go func(v T) {
+				defer func() { <-sem }()
+				r, err := fn(s.ctx, v)
+				rc <- orderedResult[R]{val: r, err: err}
+			}(v)
+			if !send(s.ctx, pending, rc) {
+				return
+			}
+		}
+	}()
+	go func() {
+		for rc := range pending {
+			var res orderedResult[R]
+			select {
+			case res = <-rc:
+			case <-s.ctx.Done():
+				continue
+			}
+			if res.err != nil {
+				s.fail(res.err)
+				continue
+			}
+			send(s.ctx, out, res.val)
+		}
+		s.finish()
+		close(out)
+	}()
+	return out, s.errc
+}
+
+// Filter 只输出fn返回true的数据
+func Filter[T any](ctx context.Context, in <-chan T, fn func(T) bool) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			v, ok := recv(ctx, in)
+			if !ok {
+				return
+			}
+			if fn(v) && !send(ctx, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Batch 将数据按size个一组输出, 一组中的第一个数据等待超过maxWait时即使不满也会输出,
+// maxWait不大于0时只按数量分组; in关闭时输出剩余的数据
+func Batch[T any](ctx context.Context, in <-chan T, size int, maxWait time.Duration) <-chan []T {
+	if size < 1 {
+		size = 1
+	}
+	out := make(chan []T)
+	go func() {
+		defer close(out)
+
+		var (
+			batch   []T
+			timer   *time.Timer
+			timeout <-chan time.Time
+		)
+		flush := func() bool {
+			if timer != nil {
+				timer.Stop()
+				timer, timeout = nil, nil
+			}
+			if len(batch) == 0 {
+				return true
+			}
+			b := batch
+			batch = nil
+			return send(ctx, out, b)
+		}
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					flush()
+					return
+				}
+				if len(batch) == 0 && maxWait > 0 {
+					timer = time.NewTimer(maxWait)
+					timeout = timer.C
+				}
+				batch = append(batch, v)
+				if len(batch) >= size && !flush() {
+					return
+				}
+			case <-timeout:
+				timer, timeout = nil, nil
+				if !flush() {
+					return
+				}
+			case <-ctx.Done():
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Merge 将多个channel的数据合并到一个channel, 所有输入都关闭后关闭输出
+func Merge[T any](ctx context.Context, chans ...<-chan T) <-chan T {
+	var (
+		out = make(chan T)
+		wg  sync.WaitGroup
+	)
+	wg.Add(len(chans))
+	for _, ch := range chans {
+		go func(ch <-chan T) {
+			defer wg.Done()
+			for {
+				v, ok := recv(ctx, ch)
+				if !ok || !send(ctx, out, v) {
+					return
+				}
+			}
+		}(ch)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// Tee 将in中的每个数据都发送到n个输出, 最慢的输出决定整体的速度
+func Tee[T any](ctx context.Context, in <-chan T, n int) []<-chan T {
+	outs := make([]chan T, n)
+	result := make([]<-chan T, n)
+	for i := range outs {
+		outs[i] = make(chan T)
+		result[i] = outs[i]
+	}
+	go func() {
+		defer func() {
+			for _, out := range outs {
+				close(out)
+			}
+		}()
+		for {
+			v, ok := recv(ctx, in)
+			if !ok {
+				return
+			}
+			for _, out := range outs {
+				if !send(ctx, out, v) {
+					return
+				}
+			}
+		}
+	}()
+	return result
+}
+
+// FirstError 等待所有阶段结束, 返回最先出现的错误
+func FirstError(errcs ...<-chan error) error {
+	var first error
+	for err := range Merge(context.Background(), errcs...) {
+		if first == nil && err != nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,357 @@
+package concurrent
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pyihe/go-pkg/errors"
+)
+
+var (
+	ErrPoolClosed   = errors.New("pool closed")
+	ErrPoolOverload = errors.New("task queue is full")
+)
+
+const (
+	defaultQueueSize   = 1024
+	defaultIdleTimeout = time.Minute
+)
+
+// SubmitPolicy 任务队列已满时Submit的处理方式
+type SubmitPolicy int
+
+const (
+	SubmitBlock  SubmitPolicy = iota // 阻塞直到队列有空位或者Pool被关闭
+	SubmitReject                     // 立即返回ErrPoolOverload
+)
+
+// PanicError 任务panic时返回的错误
+type PanicError struct {
+	Value interface{} // recover()得到的值
+	Stack []byte      // panic时的调用栈
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+func newPanicError(v interface{}) *PanicError {
+	return &PanicError{Value: v, Stack: debug.Stack()}
+}
+
+// Future 任务的执行结果
+type Future struct {
+	done   chan struct{}
+	result interface{}
+	err    error
+}
+
+func newFuture() *Future {
+	return &Future{done: make(chan struct{})}
+}
+
+func (f *Future) finish(result interface{}, err error) {
+	f.result, f.err = result, err
+	close(f.done)
+}
+
+// Done 任务执行完成时关闭
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Result 阻塞直到任务执行完成并返回结果
+func (f *Future) Result() (interface{}, error) {
+	<-f.done
+	return f.result, f.err
+}
+
+// Wait 等待任务执行完成, ctx被取消时返回ctx.Err(), 但不会取消任务
+func (f *Future) Wait(ctx context.Context) (interface{}, error) {
+	select {
+	case <-f.done:
+		return f.result, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type task struct {
+	fn     func(ctx context.Context) (interface{}, error)
+	future *Future
+}
+
+// PoolOption Pool的配置项
+type PoolOption func(*Pool)
+
+// WithWorkers 设置worker数量的范围, min等于max时为固定数量, 默认为runtime.NumCPU()
+func WithWorkers(min, max int) PoolOption {
+	return func(p *Pool) {
+		if min < 0 {
+			min = 0
+		}
+		if max < 1 {
+			max = 1
+		}
+		if min > max {
+			min = max
+		}
+		p.minWorkers, p.maxWorkers = int32(min), int32(max)
+	}
+}
+
+// WithQueueSize 任务队列的长度, 默认为1024
+func WithQueueSize(size int) PoolOption {
+	return func(p *Pool) {
+		if size >= 0 {
+			p.queueSize = size
+		}
+	}
+}
+
+// WithSubmitPolicy 任务队列已满时Submit的处理方式, 默认阻塞
+func WithSubmitPolicy(policy SubmitPolicy) PoolOption {
+	return func(p *Pool) {
+		p.policy = policy
+	}
+}
+
+// WithPanicHandler 任务panic时的回调, 任务的Future会得到*PanicError
+func WithPanicHandler(handler func(err *PanicError)) PoolOption {
+	return func(p *Pool) {
+		p.panicHandler = handler
+	}
+}
+
+// WithIdleTimeout 超过最小数量的worker空闲多久后被回收, 默认1分钟
+func WithIdleTimeout(d time.Duration) PoolOption {
+	return func(p *Pool) {
+		if d > 0 {
+			p.idleTimeout = d
+		}
+	}
+}
+
+// Pool 协程池, 使用固定或弹性数量的worker执行任务, 任务队列有界
+type Pool struct {
+	minWorkers   int32
+	maxWorkers   int32
+	queueSize    int
+	policy       SubmitPolicy
+	idleTimeout  time.Duration
+	panicHandler func(err *PanicError)
+
+	workers int32 // 当前的worker数量
+	idle    int32 // 空闲的worker数量
+	running int32 // 正在执行的任务数量
+
+	mu        sync.RWMutex
+	closed    bool
+	closing   chan struct{}
+	closeOnce sync.Once
+	queue     chan *task
+	wg        sync.WaitGroup
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// NewPool 创建协程池
+func NewPool(opts ...PoolOption) *Pool {
+	n := int32(runtime.NumCPU())
+	p := &Pool{
+		minWorkers:  n,
+		maxWorkers:  n,
+		queueSize:   defaultQueueSize,
+		idleTimeout: defaultIdleTimeout,
+		closing:     make(chan struct{}),
+	}
+	for _, op := range opts {
+		op(p)
+	}
+	p.queue = make(chan *task, p.queueSize)
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	for i := int32(0); i < p.minWorkers; i++ {
+		p.tryAddWorker(nil)
+	}
+	return p
+}
+
+// Workers 当前的worker数量
+func (p *Pool) Workers() int {
+	return int(atomic.LoadInt32(&p.workers))
+}
+
+// Running 正在执行的任务数量
+func (p *Pool) Running() int {
+	return int(atomic.LoadInt32(&p.running))
+}
+
+// Waiting 在队列中等待执行的任务数量
+func (p *Pool) Waiting() int {
+	return len(p.queue)
+}
+
+// Go 提交不关心结果的任务
+func (p *Pool) Go(fn func()) error {
+	_, err := p.Submit(func(context.Context) (interface{}, error) {
+		fn()
+		return nil, nil
+	})
+	return err
+}
+
+// Submit 提交任务, fn的ctx在Shutdown超时时会被取消
+func (p *Pool) Submit(fn func(ctx context.Context) (interface{}, error)) (*Future, error) {
+	t := &task{fn: fn, future: newFuture()}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return nil, ErrPoolClosed
+	}
+	if atomic.LoadInt32(&p.idle) == 0 && p.tryAddWorker(t) {
+		return t.future, nil
+	}
+	select {
+	case p.queue <- t:
+		// 排队的任务多于空闲的worker时扩容
+		if len(p.queue) > int(atomic.LoadInt32(&p.idle)) {
+			p.tryAddWorker(nil)
+		}
+		return t.future, nil
+	default:
+	}
+	if p.policy == SubmitReject {
+		return nil, ErrPoolOverload
+	}
+	select {
+	case p.queue <- t:
+		return t.future, nil
+	case <-p.closing:
+		return nil, ErrPoolClosed
+	}
+}
+
+func (p *Pool) tryAddWorker(first *task) bool {
+	if !p.reserveWorker() {
+		return false
+	}
+	p.wg.Add(1)
+	go p.worker(first)
+	return true
+}
+
+// reserveWorker 未达到上限时将worker数量加1
+func (p *Pool) reserveWorker() bool {
+	for {
+		n := atomic.LoadInt32(&p.workers)
+		if n >= p.maxWorkers {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&p.workers, n, n+1) {
+			return true
+		}
+	}
+}
+
+func (p *Pool) tryRemoveWorker() bool {
+	for {
+		n := atomic.LoadInt32(&p.workers)
+		if n <= p.minWorkers {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&p.workers, n, n-1) {
+			return true
+		}
+	}
+}
+
+func (p *Pool) worker(first *task) {
+	defer p.wg.Done()
+
+	if first != nil {
+		p.run(first)
+	}
+
+	timer := time.NewTimer(p.idleTimeout)
+	defer timer.Stop()
+	for {
+		atomic.AddInt32(&p.idle, 1)
+		select {
+		case t, ok := <-p.queue:
+			atomic.AddInt32(&p.idle, -1)
+			if !ok {
+				atomic.AddInt32(&p.workers, -1)
+				return
+			}
+			p.run(t)
+		case <-timer.C:
+			atomic.AddInt32(&p.idle, -1)
+			// Submit可能在idle减少前看到该worker空闲而没有扩容, 退出前重新检查队列,
+			// 有任务时恢复计数继续处理
+			if p.tryRemoveWorker() && (len(p.queue) == 0 || !p.reserveWorker()) {
+				return
+			}
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(p.idleTimeout)
+	}
+}
+
+func (p *Pool) run(t *task) {
+	if p.ctx.Err() != nil {
+		t.future.finish(nil, ErrPoolClosed)
+		return
+	}
+
+	atomic.AddInt32(&p.running, 1)
+	defer atomic.AddInt32(&p.running, -1)
+	defer func() {
+		if r := recover(); r != nil {
+			err := newPanicError(r)
+			if p.panicHandler != nil {
+				p.panicHandler(err)
+			}
+			t.future.finish(nil, err)
+		}
+	}()
+
+	result, err := t.fn(p.ctx)
+	t.future.finish(result, err)
+}
+
+// Shutdown 停止接收新任务并等待队列中的任务执行完成, ctx被取消时会取消正在执行的任务的ctx,
+// 队列中还未执行的任务以ErrPoolClosed结束, 并返回ctx.Err()
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.closeOnce.Do(func() {
+		close(p.closing)
+		p.mu.Lock()
+		p.closed = true
+		close(p.queue)
+		p.mu.Unlock()
+	})
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		return ctx.Err()
+	}
+}
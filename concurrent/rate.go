@@ -0,0 +1,303 @@
+package concurrent
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pyihe/go-pkg/errors"
+)
+
+var ErrRateExceeded = errors.New("rate limit exceeded")
+
+// RateLimiter 速率限制器
+type RateLimiter interface {
+	// Allow 当前是否允许一次请求, 允许时会消耗一次配额
+	Allow() bool
+	// Wait 阻塞直到允许一次请求或者ctx被取消, ctx的截止时间早于可以执行的时间时立即返回错误
+	Wait(ctx context.Context) error
+	// Reserve 预定一次请求, 返回需要等待的时间, 不使用时需要调用Cancel归还
+	Reserve() *Reservation
+}
+
+// Reservation 预定的请求
+type Reservation struct {
+	ok     bool
+	delay  time.Duration
+	cancel func()
+	once   sync.Once
+}
+
+// OK 是否预定成功, 请求数超过突发容量等无法满足的情况下为false
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay 执行请求前需要等待的时间
+func (r *Reservation) Delay() time.Duration {
+	return r.delay
+}
+
+// Cancel 放弃预定并归还配额
+func (r *Reservation) Cancel() {
+	if !r.ok || r.cancel == nil {
+		return
+	}
+	r.once.Do(r.cancel)
+}
+
+func waitReservation(ctx context.Context, r *Reservation) error {
+	if !r.ok {
+		return ErrRateExceeded
+	}
+	if r.delay <= 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < r.delay {
+		r.Cancel()
+		return context.DeadlineExceeded
+	}
+	timer := time.NewTimer(r.delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// TokenBucket 令牌桶限流器, 以固定速率生成令牌, 最多积累burst个
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // 每秒生成的令牌数
+	burst  int
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket 创建每秒生成rate个令牌, 容量为burst的令牌桶, 初始时桶是满的
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// advance 根据经过的时间补充令牌
+func (tb *TokenBucket) advance(now time.Time) {
+	if elapsed := now.Sub(tb.last); elapsed > 0 {
+		tb.tokens = math.Min(float64(tb.burst), tb.tokens+elapsed.Seconds()*tb.rate)
+		tb.last = now
+	}
+}
+
+func (tb *TokenBucket) Allow() bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	tb.advance(time.Now())
+	if tb.tokens < 1 {
+		return false
+	}
+	tb.tokens--
+	return true
+}
+
+func (tb *TokenBucket) Reserve() *Reservation {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	if tb.burst < 1 || (tb.rate <= 0 && tb.tokens < 1) {
+		return &Reservation{}
+	}
+	tb.advance(time.Now())
+	tb.tokens--
+	r := &Reservation{ok: true}
+	if tb.tokens < 0 {
+		r.delay = time.Duration(-tb.tokens / tb.rate * float64(time.Second))
+	}
+	r.cancel = func() {
+		tb.mu.Lock()
+		tb.advance(time.Now())
+		tb.tokens = math.Min(float64(tb.burst), tb.tokens+1)
+		tb.mu.Unlock()
+	}
+	return r
+}
+
+func (tb *TokenBucket) Wait(ctx context.Context) error {
+	return waitReservation(ctx, tb.Reserve())
+}
+
+// SlidingWindow 滑动窗口日志限流器, 任意window时间内最多允许limit次请求
+type SlidingWindow struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	log    []time.Time // 已允许(或预定)的请求时间, 升序
+}
+
+// NewSlidingWindow 创建任意window时间内最多允许limit次请求的限流器
+func NewSlidingWindow(limit int, window time.Duration) *SlidingWindow {
+	return &SlidingWindow{
+		limit:  limit,
+		window: window,
+	}
+}
+
+// prune 移除已经滑出窗口的记录
+func (sw *SlidingWindow) prune(now time.Time) {
+	boundary := now.Add(-sw.window)
+	i := 0
+	for i < len(sw.log) && !sw.log[i].After(boundary) {
+		i++
+	}
+	if i > 0 {
+		sw.log = append(sw.log[:0], sw.log[i:]...)
+	}
+}
+
+func (sw *SlidingWindow) Allow() bool {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	now := time.Now()
+	sw.prune(now)
+	if len(sw.log) >= sw.limit {
+		return false
+	}
+	sw.log = append(sw.log, now)
+	return true
+}
+
+func (sw *SlidingWindow) Reserve() *Reservation {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	if sw.limit < 1 {
+		return &Reservation{}
+	}
+	now := time.Now()
+	sw.prune(now)
+	at := now
+	if len(sw.log) >= sw.limit {
+		// 倒数第limit个记录滑出窗口后才有空位
+		at = sw.log[len(sw.log)-sw.limit].Add(sw.window)
+	}
+	sw.log = append(sw.log, at)
+	return &Reservation{
+		ok:    true,
+		delay: at.Sub(now),
+		cancel: func() {
+			sw.mu.Lock()
+			defer sw.mu.Unlock()
+			for i := len(sw.log) - 1; i >= 0; i-- {
+				if sw.log[i].Equal(at) {
+					sw.log = append(sw.log[:i], sw.log[i+1:]...)
+					return
+				}
+			}
+		},
+	}
+}
+
+func (sw *SlidingWindow) Wait(ctx context.Context) error {
+	return waitReservation(ctx, sw.Reserve())
+}
+
+type keyedEntry struct {
+	limiter  RateLimiter
+	lastSeen time.Time
+}
+
+// KeyedRateLimiter 按key(如用户ID、IP)分别限流, 长时间未使用的key会被移除
+type KeyedRateLimiter struct {
+	mu         sync.Mutex
+	limiters   map[string]*keyedEntry
+	newLimiter func() RateLimiter
+	idle       time.Duration
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+// NewKeyedRateLimiter 创建按key限流的限流器, newLimiter用于为新的key创建限流器,
+// 超过idle时间未使用的key会被移除
+func NewKeyedRateLimiter(newLimiter func() RateLimiter, idle time.Duration) *KeyedRateLimiter {
+	k := &KeyedRateLimiter{
+		limiters:   make(map[string]*keyedEntry),
+		newLimiter: newLimiter,
+		idle:       idle,
+		done:       make(chan struct{}),
+	}
+	if idle > 0 {
+		go k.evict()
+	}
+	return k
+}
+
+func (k *KeyedRateLimiter) evict() {
+	ticker := time.NewTicker(k.idle / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-k.done:
+			return
+		case now := <-ticker.C:
+			k.mu.Lock()
+			for key, e := range k.limiters {
+				if now.Sub(e.lastSeen) > k.idle {
+					delete(k.limiters, key)
+				}
+			}
+			k.mu.Unlock()
+		}
+	}
+}
+
+// Get 返回key对应的限流器, 不存在时创建
+func (k *KeyedRateLimiter) Get(key string) RateLimiter {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	e, ok := k.limiters[key]
+	if !ok {
+		e = &keyedEntry{limiter: k.newLimiter()}
+		k.limiters[key] = e
+	}
+	e.lastSeen = time.Now()
+	return e.limiter
+}
+
+// Allow key当前是否允许一次请求
+func (k *KeyedRateLimiter) Allow(key string) bool {
+	return k.Get(key).Allow()
+}
+
+// Wait 阻塞直到key允许一次请求或者ctx被取消
+func (k *KeyedRateLimiter) Wait(ctx context.Context, key string) error {
+	return k.Get(key).Wait(ctx)
+}
+
+// Reserve 为key预定一次请求
+func (k *KeyedRateLimiter) Reserve(key string) *Reservation {
+	return k.Get(key).Reserve()
+}
+
+// Len 当前的key数量
+func (k *KeyedRateLimiter) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.limiters)
+}
+
+// Close 停止移除空闲的key
+func (k *KeyedRateLimiter) Close() {
+	k.closeOnce.Do(func() {
+		close(k.done)
+	})
+}
@@ -0,0 +1,187 @@
+package concurrent
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SingleFlightResult DoChan返回的结果
+type SingleFlightResult struct {
+	Val    interface{}
+	Err    error
+	Shared bool // 结果是否与其他调用者共享(包括来自缓存的结果)
+}
+
+// SingleFlightStats 调用统计
+type SingleFlightStats struct {
+	Executed uint64 // 实际执行fn的次数
+	Shared   uint64 // 复用了其他调用结果的次数
+}
+
+// SingleFlightOption SingleFlight的选项
+type SingleFlightOption func(*SingleFlight)
+
+// WithResultTTL 成功的结果在d时间内被缓存, 期间相同key的调用直接返回该结果, 默认不缓存
+func WithResultTTL(d time.Duration) SingleFlightOption {
+	return func(s *SingleFlight) {
+		s.ttl = d
+	}
+}
+
+type flightCall struct {
+	wg    sync.WaitGroup
+	val   interface{}
+	err   error
+	dups  int
+	chans []chan<- SingleFlightResult
+}
+
+type flightMemo struct {
+	val    interface{}
+	expire time.Time
+}
+
+// SingleFlight 合并相同key的并发调用, 同一时刻每个key只执行一次fn
+type SingleFlight struct {
+	ttl time.Duration
+
+	mu    sync.Mutex
+	calls map[string]*flightCall
+	memo  map[string]*flightMemo
+
+	executed uint64
+	shared   uint64
+}
+
+// NewSingleFlight 创建SingleFlight
+func NewSingleFlight(opts ...SingleFlightOption) *SingleFlight {
+	s := &SingleFlight{
+		calls: make(map[string]*flightCall),
+		memo:  make(map[string]*flightMemo),
+	}
+	for _, op := range opts {
+		op(s)
+	}
+	return s
+}
+
+// lookup 查找缓存的结果, 调用时需持有锁
+func (s *SingleFlight) lookup(key string) (*flightMemo, bool) {
+	m, ok := s.memo[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(m.expire) {
+		delete(s.memo, key)
+		return nil, false
+	}
+	atomic.AddUint64(&s.shared, 1)
+	return m, true
+}
+
+// Do 执行fn并返回其结果, 相同key的并发调用只会执行一次, shared表示结果是否被共享,
+// fn panic时所有调用者都会得到*PanicError
+func (s *SingleFlight) Do(key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	s.mu.Lock()
+	if m, ok := s.lookup(key); ok {
+		s.mu.Unlock()
+		return m.val, nil, true
+	}
+	if c, ok := s.calls[key]; ok {
+		c.dups++
+		s.mu.Unlock()
+		atomic.AddUint64(&s.shared, 1)
+		c.wg.Wait()
+		return c.val, c.err, true
+	}
+	c := s.newCall(key)
+	s.mu.Unlock()
+
+	s.call(c, key, fn)
+	return c.val, c.err, c.dups > 0
+}
+
+// DoChan 同Do, 结果通过返回的channel传递
+func (s *SingleFlight) DoChan(key string, fn func() (interface{}, error)) <-chan SingleFlightResult {
+	ch := make(chan SingleFlightResult, 1)
+	s.mu.Lock()
+	if m, ok := s.lookup(key); ok {
+		s.mu.Unlock()
+		ch <- SingleFlightResult{Val: m.val, Shared: true}
+		return ch
+	}
+	if c, ok := s.calls[key]; ok {
+		c.dups++
+		c.chans = append(c.chans, ch)
+		s.mu.Unlock()
+		atomic.AddUint64(&s.shared, 1)
+		return ch
+	}
+	c := s.newCall(key)
+	c.chans = append(c.chans, ch)
+	s.mu.Unlock()
+
+	go s.call(c, key, fn)
+	return ch
+}
+
+// newCall 调用时需持有锁
+func (s *SingleFlight) newCall(key string) *flightCall {
+	c := &flightCall{}
+	c.wg.Add(1)
+	s.calls[key] = c
+	atomic.AddUint64(&s.executed, 1)
+	return c
+}
+
+func (s *SingleFlight) call(c *flightCall, key string, fn func() (interface{}, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.err = newPanicError(r)
+		}
+
+		s.mu.Lock()
+		// Forget之后可能已经有新的调用, 只移除自己
+		if s.calls[key] == c {
+			delete(s.calls, key)
+			if c.err == nil && s.ttl > 0 {
+				m := &flightMemo{val: c.val, expire: time.Now().Add(s.ttl)}
+				s.memo[key] = m
+				time.AfterFunc(s.ttl, func() {
+					s.mu.Lock()
+					if s.memo[key] == m {
+						delete(s.memo, key)
+					}
+					s.mu.Unlock()
+				})
+			}
+		}
+		chans := c.chans
+		shared := c.dups > 0
+		s.mu.Unlock()
+
+		c.wg.Done()
+		for _, ch := range chans {
+			ch <- SingleFlightResult{Val: c.val, Err: c.err, Shared: shared}
+		}
+	}()
+
+	c.val, c.err = fn()
+}
+
+// Forget 忘记key正在进行的调用和缓存的结果, 之后相同key的调用会重新执行fn
+func (s *SingleFlight) Forget(key string) {
+	s.mu.Lock()
+	delete(s.calls, key)
+	delete(s.memo, key)
+	s.mu.Unlock()
+}
+
+// Stats 调用统计
+func (s *SingleFlight) Stats() SingleFlightStats {
+	return SingleFlightStats{
+		Executed: atomic.LoadUint64(&s.executed),
+		Shared:   atomic.LoadUint64(&s.shared),
+	}
+}
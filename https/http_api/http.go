@@ -8,6 +8,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/pyihe/go-pkg/concurrent"
 	"github.com/pyihe/go-pkg/errors"
 	"github.com/pyihe/go-pkg/syncs"
 	"github.com/swaggo/files"
@@ -143,6 +144,41 @@ func MidCORS() gin.HandlerFunc {
 	}
 }
 
+// MidRateLimit 按key限流, key为nil时按客户端IP限流, 超出限制时返回429
+func MidRateLimit(limiter *concurrent.KeyedRateLimiter, key func(*gin.Context) string) gin.HandlerFunc {
+	if key == nil {
+		key = func(c *gin.Context) string {
+			return c.ClientIP()
+		}
+	}
+	return func(c *gin.Context) {
+		if !limiter.Allow(key(c)) {
+			c.AbortWithStatus(http.StatusTooManyRequests)
+			return
+		}
+		c.Next()
+	}
+}
+
+// MidAdaptiveLimit 自适应限制并发请求数, 超出上限时返回503, 状态码为5xx的请求计为失败
+func MidAdaptiveLimit(limiter *concurrent.AdaptiveLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		permit, err := limiter.TryAcquire()
+		if err != nil {
+			c.AbortWithStatus(http.StatusServiceUnavailable)
+			return
+		}
+		defer func() {
+			if c.Writer.Status() >= http.StatusInternalServerError {
+				permit.Failure()
+			} else {
+				permit.Success()
+			}
+		}()
+		c.Next()
+	}
+}
+
 func IndentedJSON(c *gin.Context, err error, data interface{}) {
 	status := http.StatusOK
 	if err != nil {